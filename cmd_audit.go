@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"lockin/internal/audit"
+	"lockin/internal/store"
+)
+
+// runAudit prints the vault's audit report, grouped by kind of problem
+func runAudit() error {
+	vault, err := store.NewFileVault()
+	if err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	defer vault.Close()
+
+	if err := unlockVault(vault); err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	report, err := audit.Run(vault, store.AuditStaleAge())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Checked %d entries.\n", report.Checked)
+	if len(report.Findings) == 0 {
+		fmt.Println("✓ No problems found")
+		return nil
+	}
+
+	for _, kind := range audit.Kinds {
+		var findings []audit.Finding
+		width := 0
+		for _, f := range report.Findings {
+			if f.Kind == kind {
+				findings = append(findings, f)
+				width = max(width, len(f.Entry.Name))
+			}
+		}
+		if len(findings) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n%ss (%d)\n", kind.Label(), len(findings))
+		for _, f := range findings {
+			fmt.Printf("  %-*s  %s\n", width, f.Entry.Name, f.Detail)
+		}
+	}
+	return nil
+}
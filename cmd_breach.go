@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"lockin/internal/breach"
+	"lockin/internal/store"
+)
+
+// runBreachCheck looks up every password in the vault in a local copy of the
+// Pwned Passwords list and stores the results for the audit and detail views
+func runBreachCheck(args []string) error {
+	vault, err := store.NewFileVault()
+	if err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	defer vault.Close()
+
+	flags := flag.NewFlagSet("breach-check", flag.ExitOnError)
+	path := flags.String("file", store.BreachListPath(), "Pwned Passwords file (SHA-1, ordered by hash)")
+	flags.Parse(args)
+	if *path == "" {
+		return errors.New("no Pwned Passwords file; set breach_list in config.yaml or pass --file")
+	}
+
+	list, err := breach.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer list.Close()
+
+	if err := unlockVault(vault); err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	syncResult, err := vault.CheckBreaches(list)
+	if err != nil {
+		return err
+	}
+
+	entries, err := vault.List()
+	if err != nil {
+		return err
+	}
+	breaches, err := vault.Breaches()
+	if err != nil {
+		return err
+	}
+
+	// Entries come sorted by name
+	checked := 0
+	var breached []store.Entry
+	width := 0
+	for _, entry := range entries {
+		b, ok := breaches[entry.ID]
+		if !ok {
+			continue
+		}
+		checked++
+		if b.Count > 0 {
+			breached = append(breached, entry)
+			width = max(width, len(entry.Name))
+		}
+	}
+
+	fmt.Printf("Checked %d passwords.\n", checked)
+	if syncResult.SyncError != nil {
+		fmt.Printf("✗ Sync failed: %v\n", syncResult.SyncError)
+	}
+	if *path != store.BreachListPath() {
+		fmt.Println("Set breach_list in config.yaml to also check passwords as they are saved.")
+	}
+
+	if len(breached) == 0 {
+		fmt.Println("✓ No passwords found in breaches")
+		return nil
+	}
+
+	fmt.Printf("\nBreached passwords (%d)\n", len(breached))
+	for _, entry := range breached {
+		fmt.Printf("  %-*s  %s\n", width, entry.Name, breach.Describe(breaches[entry.ID].Count))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"lockin/internal/generator"
+	"lockin/internal/store"
+)
+
+// runGenerate prints generated passwords or passphrases, starting from the
+// defaults in config.yaml. The vault is not opened.
+func runGenerate(args []string) error {
+	if err := store.LoadConfig(); err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	defaults := store.GeneratorOptions()
+
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	passphrase := flags.Bool("passphrase", defaults.Mode == generator.ModePassphrase, "generate a diceware passphrase")
+	length := flags.Int("length", defaults.Length, "password length")
+	lower := flags.Bool("lower", defaults.Lowercase, "use lowercase letters")
+	upper := flags.Bool("upper", defaults.Uppercase, "use uppercase letters")
+	digits := flags.Bool("digits", defaults.Digits, "use digits")
+	symbols := flags.Bool("symbols", defaults.Symbols, "use symbols")
+	noAmbiguous := flags.Bool("exclude-ambiguous", defaults.ExcludeAmbiguous, "leave out look-alike characters such as l, 1, O and 0")
+	words := flags.Int("words", defaults.Words, "passphrase word count")
+	separator := flags.String("separator", defaults.Separator, "passphrase word separator")
+	count := flags.Int("n", 1, "number of passwords to generate")
+	flags.Parse(args)
+
+	options := generator.Options{
+		Mode:             generator.ModePassword,
+		Length:           *length,
+		Lowercase:        *lower,
+		Uppercase:        *upper,
+		Digits:           *digits,
+		Symbols:          *symbols,
+		ExcludeAmbiguous: *noAmbiguous,
+		Words:            *words,
+		Separator:        *separator,
+	}
+	if *passphrase {
+		options.Mode = generator.ModePassphrase
+	}
+
+	for i := 0; i < *count; i++ {
+		password, err := generator.Generate(options)
+		if err != nil {
+			return err
+		}
+		fmt.Println(password)
+	}
+	return nil
+}
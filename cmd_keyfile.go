@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"lockin/internal/store"
+)
+
+// runKeyfile sets, replaces or removes the keyfile needed to unlock the vault
+func runKeyfile(args []string) error {
+	flags := flag.NewFlagSet("keyfile", flag.ExitOnError)
+	clearKeyfile := flags.Bool("clear", false, "stop requiring a keyfile")
+	flags.Parse(args)
+
+	path := flags.Arg(0)
+	if *clearKeyfile == (path != "") {
+		return errors.New("usage: lockin keyfile path | lockin keyfile --clear")
+	}
+
+	vault, err := store.NewFileVault()
+	if err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	defer vault.Close()
+
+	if !vault.Exists() {
+		return store.ErrVaultNotFound
+	}
+	password, err := readSecret("Master password: ")
+	if err != nil {
+		return err
+	}
+	if err := vault.Unlock(password); err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	syncResult, err := vault.ChangeKeyfile(password, path)
+	if err != nil {
+		return err
+	}
+
+	msg := "✓ Keyfile requirement removed"
+	if path != "" {
+		msg = "✓ Vault now requires keyfile " + path
+	}
+	if syncResult.SyncEnabled {
+		if syncResult.SyncError != nil {
+			msg += " (sync failed)"
+		} else {
+			msg += " (synced)"
+		}
+	}
+	fmt.Println(msg)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"lockin/internal/store"
+)
+
+// runRecover resets a forgotten master password using the recovery key
+func runRecover() error {
+	vault, err := store.NewFileVault()
+	if err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	defer vault.Close()
+
+	if !vault.Exists() {
+		return store.ErrVaultNotFound
+	}
+	if !vault.HasRecoveryKey() {
+		return store.ErrNoRecoveryKey
+	}
+
+	recoveryKey, err := readSecret("Recovery key: ")
+	if err != nil {
+		return err
+	}
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	syncResult, err := vault.Recover(recoveryKey, password)
+	if err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	msg := "✓ Master password reset"
+	if syncResult.SyncEnabled {
+		if syncResult.SyncError != nil {
+			msg += " (sync failed)"
+		} else {
+			msg += " (synced)"
+		}
+	}
+	fmt.Println(msg)
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"lockin/internal/sshagent"
+	"lockin/internal/store"
+)
+
+// runSSHAgent serves the vault's SSH keys over a Unix socket until
+// interrupted or, with auto-lock enabled, until the agent has been idle for
+// the auto-lock timeout
+func runSSHAgent(args []string) error {
+	flags := flag.NewFlagSet("ssh-agent", flag.ExitOnError)
+	socket := flags.String("socket", filepath.Join(store.GetConfigDir(), "agent.sock"), "path of the agent socket")
+	noConfirm := flags.Bool("no-confirm", false, "sign without asking for confirmation")
+	flags.Parse(args)
+
+	vault, err := store.NewFileVault()
+	if err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	defer vault.Close()
+
+	if err := unlockVault(vault); err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	// Replace a socket left behind by an agent that did not exit cleanly
+	if info, err := os.Lstat(*socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and is not a socket", *socket)
+		}
+		os.Remove(*socket)
+	}
+	listener, err := listenUnix(*socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socket)
+
+	// Closed on shutdown so a pending confirmation does not hold the vault open
+	stopped := make(chan struct{})
+	var confirm sshagent.ConfirmFunc
+	if !*noConfirm {
+		confirm = confirmKeyUse(stopped)
+	}
+	a := sshagent.New(vault, confirm)
+
+	// Stop on Ctrl+C or SIGTERM
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	// Lock like the terminal UI does once the agent has not been used for a while
+	if timeout := store.AutoLockTimeout(); timeout > 0 {
+		go func() {
+			for range time.Tick(time.Second) {
+				if time.Since(a.LastUsed()) >= timeout {
+					fmt.Println("Vault locked after inactivity")
+					listener.Close()
+					return
+				}
+			}
+		}()
+	}
+
+	fmt.Printf("SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", *socket)
+	fmt.Println("Serving SSH keys from the vault. Press Ctrl+C to stop.")
+	err = a.Serve(listener)
+	close(stopped)
+	a.LockVault()
+	return err
+}
+
+// confirmTimeout is how long a confirmation prompt waits before denying
+const confirmTimeout = 30 * time.Second
+
+// confirmKeyUse returns a ConfirmFunc that asks on the terminal before each
+// signature. Answering "a" allows a key for the rest of the session. Prompts
+// still waiting once stopped is closed are denied.
+func confirmKeyUse(stopped <-chan struct{}) sshagent.ConfirmFunc {
+	// Read answers in the background so an unanswered prompt can time out
+	answers := make(chan string)
+	go func() {
+		for {
+			line, err := readLine("")
+			if err != nil {
+				close(answers)
+				return
+			}
+			answers <- line
+		}
+	}()
+
+	allowed := make(map[string]bool)
+	return func(name string) bool {
+		if allowed[name] {
+			return true
+		}
+
+		fmt.Printf("Allow use of SSH key '%s'? [y]es / [a]lways / [N]o: ", name)
+		var answer string
+		select {
+		case answer = <-answers:
+		case <-time.After(confirmTimeout):
+			fmt.Println("(timed out)")
+			return false
+		case <-stopped:
+			fmt.Println()
+			return false
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true
+		case "a", "always":
+			allowed[name] = true
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// runSSHImport imports SSH private keys into the vault, by default every
+// ~/.ssh/id_* key
+func runSSHImport(paths []string) error {
+	if len(paths) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		matches, err := filepath.Glob(filepath.Join(home, ".ssh", "id_*"))
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			if !strings.HasSuffix(path, ".pub") {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no keys found in %s", filepath.Join(home, ".ssh"))
+		}
+	}
+
+	vault, err := store.NewFileVault()
+	if err != nil {
+		return fmt.Errorf("opening vault: %w", err)
+	}
+	defer vault.Close()
+
+	if err := unlockVault(vault); err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	failed := 0
+	for _, path := range paths {
+		if err := importSSHKey(vault, path); err != nil {
+			fmt.Printf("✗ %s: %v\n", path, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d keys not imported", failed, len(paths))
+	}
+	fmt.Println("Once you have checked the imported keys work through `lockin ssh-agent`, the key files can be removed from disk.")
+	return nil
+}
+
+// importSSHKey adds one key file to the vault, asking for its passphrase if needed
+func importSSHKey(vault *store.FileVault, path string) error {
+	var entry store.Entry
+	err := store.LoadSSHKey(&entry, path, "")
+	if errors.Is(err, store.ErrSSHPassphraseRequired) {
+		passphrase, perr := readSecret(fmt.Sprintf("Passphrase for %s: ", path))
+		if perr != nil {
+			return perr
+		}
+		err = store.LoadSSHKey(&entry, path, passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := vault.Add(entry); err != nil {
+		if err == store.ErrDuplicateEntry {
+			return fmt.Errorf("an entry named '%s' already exists", entry.Name)
+		}
+		return err
+	}
+
+	fmt.Printf("✓ Imported %s\n", entry.Name)
+	return nil
+}
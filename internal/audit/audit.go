@@ -0,0 +1,212 @@
+// Package audit checks the entries of a vault for passwords and settings
+// that put accounts at risk: breached, reused, weak or old passwords, sites
+// signed in to over plain HTTP and sites saved more than once under different
+// usernames.
+package audit
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"lockin/internal/breach"
+	"lockin/internal/store"
+	"lockin/internal/strength"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of problem a finding reports
+type Kind int
+
+const (
+	KindBreached Kind = iota
+	KindReused
+	KindWeak
+	KindStale
+	KindInsecureURL
+	KindDuplicateURL
+)
+
+// Kinds lists every kind of finding in report order
+var Kinds = []Kind{KindBreached, KindReused, KindWeak, KindStale, KindInsecureURL, KindDuplicateURL}
+
+var kindLabels = map[Kind]string{
+	KindBreached:     "Breached password",
+	KindReused:       "Reused password",
+	KindWeak:         "Weak password",
+	KindStale:        "Old password",
+	KindInsecureURL:  "Insecure URL",
+	KindDuplicateURL: "Duplicate URL",
+}
+
+// Label names the kind of problem, e.g. "Reused password"
+func (k Kind) Label() string {
+	return kindLabels[k]
+}
+
+// Finding is one problem with one entry
+type Finding struct {
+	Kind   Kind
+	Entry  store.Entry // without its password
+	Detail string
+}
+
+// Report is the result of auditing a vault
+type Report struct {
+	Findings []Finding
+	Checked  int // entries audited
+}
+
+// Count returns the number of findings of the given kind
+func (r *Report) Count(kind Kind) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Run audits every entry in the vault. Passwords not changed for staleAfter
+// are reported as old; 0 skips that check. Only entries with a username and
+// password are checked for password problems, and empty passwords are ignored.
+// Breaches come from the results stored by the last breach check.
+func Run(vault *store.FileVault, staleAfter time.Duration) (*Report, error) {
+	entries, err := vault.List()
+	if err != nil {
+		return nil, err
+	}
+	breaches, err := vault.Breaches()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Checked: len(entries)}
+	add := func(kind Kind, entry store.Entry, format string, args ...any) {
+		report.Findings = append(report.Findings, Finding{Kind: kind, Entry: entry, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	passwords := make(map[[sha256.Size]byte][]store.Entry)
+	for _, entry := range entries {
+		if !entry.Type.HasLogin() {
+			continue
+		}
+
+		password, err := vault.Password(entry.ID)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting password of '%s': %w", entry.Name, err)
+		}
+		if password.Len() == 0 {
+			password.Destroy()
+			continue
+		}
+
+		// Only a hash is kept to find reuse
+		sum := sha256.Sum256(password.Bytes())
+		passwords[sum] = append(passwords[sum], entry)
+
+		result := strength.Check(string(password.Bytes()), entry.Name, entry.Username, entry.URL)
+		password.Destroy()
+		if b, ok := breaches[entry.ID]; ok && b.Count > 0 {
+			add(KindBreached, entry, "%s", breach.Describe(b.Count))
+		}
+		if result.Weak() {
+			detail := result.Score.String()
+			if result.Warning != "" {
+				detail += ": " + result.Warning
+			}
+			add(KindWeak, entry, "%s", detail)
+		}
+
+		if age := time.Since(time.Unix(entry.UpdatedAt, 0)); staleAfter > 0 && age >= staleAfter {
+			add(KindStale, entry, "Not changed in %s", formatAge(age))
+		}
+	}
+
+	for _, group := range passwords {
+		if len(group) < 2 {
+			continue
+		}
+		for _, entry := range group {
+			add(KindReused, entry, "Same password as %s", otherNames(group, entry))
+		}
+	}
+
+	sites := make(map[string][]store.Entry)
+	for _, entry := range entries {
+		if !entry.Type.HasURL() || entry.URL == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(entry.URL)), "http://") {
+			add(KindInsecureURL, entry, "Signs in over unencrypted http://")
+		}
+		if entry.Username != "" {
+			site := normalizeURL(entry.URL)
+			sites[site] = append(sites[site], entry)
+		}
+	}
+
+	for _, group := range sites {
+		for _, entry := range group {
+			var others []store.Entry
+			for _, other := range group {
+				if !strings.EqualFold(other.Username, entry.Username) {
+					others = append(others, other)
+				}
+			}
+			if len(others) > 0 {
+				add(KindDuplicateURL, entry, "Same URL as %s with a different username", otherNames(others, entry))
+			}
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return strings.ToLower(a.Entry.Name) < strings.ToLower(b.Entry.Name)
+	})
+	return report, nil
+}
+
+// otherNames lists the names of the entries in group other than entry
+func otherNames(group []store.Entry, entry store.Entry) string {
+	var names []string
+	for _, other := range group {
+		if other.ID != entry.ID {
+			names = append(names, "'"+other.Name+"'")
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// normalizeURL reduces a URL to the host and path it points at, so
+// "https://www.example.com/" and "example.com" are the same site
+func normalizeURL(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return strings.TrimPrefix(u.Host, "www.") + strings.TrimSuffix(u.Path, "/")
+}
+
+// formatAge renders a duration in months or years
+func formatAge(age time.Duration) string {
+	months := int(age.Hours() / 24 / 30)
+	switch {
+	case months >= 24:
+		return fmt.Sprintf("%d years", months/12)
+	case months == 1:
+		return "1 month"
+	default:
+		return fmt.Sprintf("%d months", months)
+	}
+}
@@ -0,0 +1,217 @@
+// Package breach looks passwords up in a local copy of the Have I Been Pwned
+// Pwned Passwords list, so they can be checked against known breaches without
+// sending anything over the network.
+//
+// The list must be the SHA-1 version ordered by hash, one "HASH:COUNT" line
+// per password, as written by the official downloader. Lines are found by
+// binary search over the file itself, so no index has to be built.
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+var ErrInvalidList = errors.New("not a Pwned Passwords SHA-1 list ordered by hash")
+
+// hashLen is the length of a hex encoded SHA-1 hash
+const hashLen = 2 * sha1.Size
+
+// scanWindow is the span of the file below which lookups read line by line
+// instead of seeking
+const scanWindow = 8 << 10
+
+// List is an open Pwned Passwords file
+type List struct {
+	file *os.File
+	size int64
+}
+
+// Open opens the Pwned Passwords file at path, refusing files that do not
+// look like the SHA-1 list ordered by hash
+func Open(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	l := &List{file: file, size: info.Size()}
+	if err := l.verify(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Close closes the file
+func (l *List) Close() error {
+	return l.file.Close()
+}
+
+// verify checks that the first lines are well formed and in order, which
+// catches the NTLM and count-ordered downloads before they give wrong answers
+func (l *List) verify() error {
+	r := bufio.NewReader(io.NewSectionReader(l.file, 0, l.size))
+	var previous []byte
+	for i := 0; i < 3; i++ {
+		line, err := r.ReadSlice('\n')
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		hash, _, ok := parseLine(line)
+		if !ok || bytes.Compare(previous, hash) > 0 {
+			return ErrInvalidList
+		}
+		previous = append(previous[:0], hash...)
+	}
+	if previous == nil {
+		return ErrInvalidList
+	}
+	return nil
+}
+
+// Count returns how many times password appears in breaches, or 0 if it is
+// not in the list
+func (l *List) Count(password []byte) (int, error) {
+	sum := sha1.Sum(password)
+	target := make([]byte, hashLen)
+	hex.Encode(target, sum[:])
+	return l.lookup(bytes.ToUpper(target))
+}
+
+// lookup finds the line for an upper case hex hash. Every line starting
+// before lo has a smaller hash and every line starting at or after hi has an
+// equal or larger one, so the search narrows the two together and then reads
+// forward from lo.
+func (l *List) lookup(target []byte) (int, error) {
+	lo, hi := int64(0), l.size
+	for hi-lo > scanWindow {
+		mid := lo + (hi-lo)/2
+		start, line, err := l.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if line == nil || start >= hi {
+			hi = mid
+			continue
+		}
+		hash, _, ok := parseLine(line)
+		if !ok {
+			return 0, ErrInvalidList
+		}
+		if bytes.Compare(hash, target) < 0 {
+			lo = start + int64(len(line))
+		} else {
+			hi = start
+		}
+	}
+
+	// Lines found out of order here mean the file is not sorted and the search
+	// above cannot be trusted
+	r := bufio.NewReader(io.NewSectionReader(l.file, lo, l.size-lo))
+	var previous []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		if len(line) > 0 {
+			hash, count, ok := parseLine(line)
+			if !ok || bytes.Compare(previous, hash) > 0 {
+				return 0, ErrInvalidList
+			}
+			previous = hash
+			switch bytes.Compare(hash, target) {
+			case 0:
+				return count, nil
+			case 1:
+				return 0, nil
+			}
+		}
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// lineAt returns the first line, with its newline, that starts at or after
+// offset (which must be past 0), and where it starts. The line is nil past
+// the last line.
+func (l *List) lineAt(offset int64) (int64, []byte, error) {
+	// A line starts at or after offset only if the newline before it is at
+	// offset-1 or later
+	start := offset - 1
+	r := bufio.NewReader(io.NewSectionReader(l.file, start, l.size-start))
+	skipped, err := r.ReadSlice('\n')
+	if err == io.EOF {
+		return l.size, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	start += int64(len(skipped))
+
+	line, err := r.ReadSlice('\n')
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	if len(line) == 0 {
+		return start, nil, nil
+	}
+	return start, line, nil
+}
+
+// parseLine splits a "HASH:COUNT" line into its upper case hash and count
+func parseLine(line []byte) ([]byte, int, bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	if len(line) < hashLen+2 || line[hashLen] != ':' {
+		return nil, 0, false
+	}
+	hash := bytes.ToUpper(line[:hashLen])
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'A' || c > 'F') {
+			return nil, 0, false
+		}
+	}
+	count, err := strconv.Atoi(string(line[hashLen+1:]))
+	if err != nil || count < 0 {
+		return nil, 0, false
+	}
+	return hash, count, true
+}
+
+// Describe summarises a count for display, e.g. "Seen 3,861,493 times in data breaches"
+func Describe(count int) string {
+	if count == 1 {
+		return "Seen once in data breaches"
+	}
+	return fmt.Sprintf("Seen %s times in data breaches", groupDigits(count))
+}
+
+// groupDigits writes n with commas between groups of three digits
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	var b []byte
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b = append(b, ',')
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
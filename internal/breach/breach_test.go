@@ -0,0 +1,193 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// listLine is one line of a test list
+type listLine struct {
+	hash  string
+	count int
+}
+
+// testLines returns n lines for the passwords "password0" to "password<n-1>",
+// sorted by hash, each seen i+1 times
+func testLines(n int) []listLine {
+	lines := make([]listLine, n)
+	for i := range lines {
+		sum := sha1.Sum([]byte(fmt.Sprintf("password%d", i)))
+		lines[i] = listLine{strings.ToUpper(hex.EncodeToString(sum[:])), i + 1}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].hash < lines[j].hash })
+	return lines
+}
+
+// writeList writes lines to a file with the given line ending, leaving the
+// last line unterminated when trailing is false
+func writeList(t *testing.T, lines []listLine, newline string, trailing bool) string {
+	t.Helper()
+	var b strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&b, "%s:%d", line.hash, line.count)
+		if trailing || i < len(lines)-1 {
+			b.WriteString(newline)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// absentHash returns a hash that sorts right after hash and is not in the list
+func absentHash(hash string) string {
+	return hash[:len(hash)-1] + "G"
+}
+
+func TestLookup(t *testing.T) {
+	// Large enough that lookups go through the binary search, not only the scan
+	lines := testLines(5000)
+
+	formats := []struct {
+		name     string
+		newline  string
+		trailing bool
+	}{
+		{"CRLF", "\r\n", true},
+		{"LF", "\n", true},
+		{"no trailing newline", "\r\n", false},
+	}
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			list, err := Open(writeList(t, lines, format.newline, format.trailing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer list.Close()
+
+			for _, line := range lines {
+				count, err := list.lookup([]byte(line.hash))
+				if err != nil || count != line.count {
+					t.Fatalf("lookup(%s) = %d, %v, want %d", line.hash, count, err, line.count)
+				}
+				// Bytes past F sort after every hex digit, so this falls between lines
+				count, err = list.lookup([]byte(absentHash(line.hash)))
+				if err != nil || count != 0 {
+					t.Fatalf("lookup(absent after %s) = %d, %v, want 0", line.hash, count, err)
+				}
+			}
+			if count, err := list.lookup([]byte(strings.Repeat("0", hashLen))); err != nil || count != 0 {
+				t.Errorf("lookup(before first) = %d, %v, want 0", count, err)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	lines := testLines(5000)
+	path := writeList(t, lines, "\r\n", true)
+	list, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+
+	tests := []struct {
+		password string
+		count    int
+	}{
+		{"password0", 1},
+		{"password1234", 1235},
+		{"password4999", 5000},
+		{"password5000", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		count, err := list.Count([]byte(tt.password))
+		if err != nil || count != tt.count {
+			t.Errorf("Count(%q) = %d, %v, want %d", tt.password, count, err, tt.count)
+		}
+	}
+}
+
+func TestLowercaseHashes(t *testing.T) {
+	lines := testLines(100)
+	for i := range lines {
+		lines[i].hash = strings.ToLower(lines[i].hash)
+	}
+	list, err := Open(writeList(t, lines, "\n", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+
+	if count, err := list.Count([]byte("password42")); err != nil || count != 43 {
+		t.Errorf("Count = %d, %v, want 43", count, err)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	sorted := testLines(3)
+	tests := map[string]string{
+		"empty":      "",
+		"not hashes": "hello\nworld\n",
+		// NTLM hashes are 32 characters
+		"NTLM":          "8846F7EAEE8FB117AD06BDD830B7586C:1\n",
+		"missing count": sorted[0].hash + ":\n",
+		"out of order":  fmt.Sprintf("%s:1\n%s:1\n%s:1\n", sorted[2].hash, sorted[0].hash, sorted[1].hash),
+	}
+	for name, contents := range tests {
+		path := filepath.Join(t.TempDir(), "pwned.txt")
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if list, err := Open(path); !errors.Is(err, ErrInvalidList) {
+			if list != nil {
+				list.Close()
+			}
+			t.Errorf("%s: Open error = %v, want ErrInvalidList", name, err)
+		}
+	}
+}
+
+func TestLookupOutOfOrder(t *testing.T) {
+	// Sorted at the start, so Open accepts it
+	lines := testLines(10)
+	lines[6], lines[7] = lines[7], lines[6]
+	list, err := Open(writeList(t, lines, "\n", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+
+	if _, err := list.lookup([]byte(lines[9].hash)); !errors.Is(err, ErrInvalidList) {
+		t.Errorf("lookup past the disorder = %v, want ErrInvalidList", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		count int
+		want  string
+	}{
+		{1, "Seen once in data breaches"},
+		{2, "Seen 2 times in data breaches"},
+		{999, "Seen 999 times in data breaches"},
+		{1000, "Seen 1,000 times in data breaches"},
+		{3861493, "Seen 3,861,493 times in data breaches"},
+	}
+	for _, tt := range tests {
+		if got := Describe(tt.count); got != tt.want {
+			t.Errorf("Describe(%d) = %q, want %q", tt.count, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Package generator creates random passwords and diceware passphrases. Words
+// are drawn from the EFF large wordlist, which is embedded in the binary.
+package generator
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Generation modes
+const (
+	ModePassword   = "password"
+	ModePassphrase = "passphrase"
+)
+
+// Limits on the length of generated passwords and passphrases
+const (
+	MinLength = 8
+	MaxLength = 128
+	MinWords  = 3
+	MaxWords  = 20
+)
+
+// Character sets
+const (
+	lowercase = "abcdefghijklmnopqrstuvwxyz"
+	uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits    = "0123456789"
+	symbols   = "!@#$%^&*()-_=+[]{};:,.<>/?~"
+
+	// Characters easily mistaken for one another when read or typed
+	ambiguous = "Il1O0o|"
+)
+
+// ErrNoCharacters is returned when every character set is turned off
+var ErrNoCharacters = errors.New("at least one character set is required")
+
+// Options controls what Generate produces
+type Options struct {
+	Mode string `yaml:"mode"` // ModePassword or ModePassphrase
+
+	// Passwords
+	Length           int  `yaml:"length"`
+	Lowercase        bool `yaml:"lowercase"`
+	Uppercase        bool `yaml:"uppercase"`
+	Digits           bool `yaml:"digits"`
+	Symbols          bool `yaml:"symbols"`
+	ExcludeAmbiguous bool `yaml:"exclude_ambiguous"`
+
+	// Passphrases
+	Words     int    `yaml:"words"`
+	Separator string `yaml:"separator"`
+}
+
+// DefaultOptions generates 20-character passwords from every character set
+var DefaultOptions = Options{
+	Mode:      ModePassword,
+	Length:    20,
+	Lowercase: true,
+	Uppercase: true,
+	Digits:    true,
+	Symbols:   true,
+	Words:     6,
+	Separator: "-",
+}
+
+//go:embed eff_large_wordlist.txt
+var effWordlist string
+
+// wordlist returns the words of the EFF large wordlist, parsed on first use
+var wordlist = sync.OnceValue(func() []string {
+	words := make([]string, 0, 7776)
+	scanner := bufio.NewScanner(strings.NewReader(effWordlist))
+	for scanner.Scan() {
+		// Each line is a dice roll and a word separated by a tab
+		if _, word, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// Validate checks that the options can produce a password
+func (o Options) Validate() error {
+	switch o.Mode {
+	case ModePassword:
+		if o.Length < MinLength || o.Length > MaxLength {
+			return fmt.Errorf("length must be between %d and %d", MinLength, MaxLength)
+		}
+		if len(o.sets()) == 0 {
+			return ErrNoCharacters
+		}
+		if o.Length < len(o.sets()) {
+			return fmt.Errorf("length must be at least %d to use every character set", len(o.sets()))
+		}
+	case ModePassphrase:
+		if o.Words < MinWords || o.Words > MaxWords {
+			return fmt.Errorf("word count must be between %d and %d", MinWords, MaxWords)
+		}
+	default:
+		return fmt.Errorf("unknown mode '%s'", o.Mode)
+	}
+	return nil
+}
+
+// sets returns the enabled character sets, without ambiguous characters if
+// they are excluded
+func (o Options) sets() []string {
+	var sets []string
+	for _, set := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{o.Lowercase, lowercase},
+		{o.Uppercase, uppercase},
+		{o.Digits, digits},
+		{o.Symbols, symbols},
+	} {
+		if !set.enabled {
+			continue
+		}
+		chars := set.chars
+		if o.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguous, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		sets = append(sets, chars)
+	}
+	return sets
+}
+
+// Generate creates a password or passphrase according to the options
+func Generate(o Options) (string, error) {
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+	if o.Mode == ModePassphrase {
+		return passphrase(o)
+	}
+	return password(o)
+}
+
+// password picks Length characters from the enabled sets, with at least one
+// from each set
+func password(o Options) (string, error) {
+	sets := o.sets()
+	pool := strings.Join(sets, "")
+
+	chars := make([]byte, o.Length)
+	for i := range chars {
+		// The first characters cover every set; shuffling moves them around
+		from := pool
+		if i < len(sets) {
+			from = sets[i]
+		}
+		n, err := randomInt(len(from))
+		if err != nil {
+			return "", err
+		}
+		chars[i] = from[n]
+	}
+
+	// Fisher-Yates shuffle
+	for i := len(chars) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+	return string(chars), nil
+}
+
+// passphrase picks Words words from the wordlist
+func passphrase(o Options) (string, error) {
+	list := wordlist()
+	words := make([]string, o.Words)
+	for i := range words {
+		n, err := randomInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		words[i] = list[n]
+	}
+	return strings.Join(words, o.Separator), nil
+}
+
+// Entropy returns the approximate strength in bits of what the options
+// generate
+func (o Options) Entropy() float64 {
+	if o.Mode == ModePassphrase {
+		return float64(o.Words) * math.Log2(float64(len(wordlist())))
+	}
+	pool := len(strings.Join(o.sets(), ""))
+	if pool == 0 {
+		return 0
+	}
+	return float64(o.Length) * math.Log2(float64(pool))
+}
+
+// Describe summarizes the options, e.g. "20 characters, a-z A-Z 0-9 symbols"
+func (o Options) Describe() string {
+	if o.Mode == ModePassphrase {
+		return fmt.Sprintf("%d words separated by '%s'", o.Words, o.Separator)
+	}
+
+	var sets []string
+	for _, set := range []struct {
+		enabled bool
+		name    string
+	}{
+		{o.Lowercase, "a-z"},
+		{o.Uppercase, "A-Z"},
+		{o.Digits, "0-9"},
+		{o.Symbols, "symbols"},
+	} {
+		if set.enabled {
+			sets = append(sets, set.name)
+		}
+	}
+	s := fmt.Sprintf("%d characters, %s", o.Length, strings.Join(sets, " "))
+	if o.ExcludeAmbiguous {
+		s += ", no look-alikes"
+	}
+	return s
+}
+
+// randomInt returns a uniformly random number in [0, n)
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPasswordCharacterSets(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"default", DefaultOptions},
+		{"lowercase only", Options{Mode: ModePassword, Length: MinLength, Lowercase: true}},
+		{"digits and symbols", Options{Mode: ModePassword, Length: 12, Digits: true, Symbols: true}},
+		// The shortest length allowed, where half the characters are forced
+		{"one of each", Options{Mode: ModePassword, Length: MinLength, Lowercase: true, Uppercase: true, Digits: true, Symbols: true, ExcludeAmbiguous: true}},
+		{"no look-alikes", Options{Mode: ModePassword, Length: MaxLength, Lowercase: true, Uppercase: true, Digits: true, Symbols: true, ExcludeAmbiguous: true}},
+	}
+	for _, tt := range tests {
+		sets := tt.opts.sets()
+		pool := strings.Join(sets, "")
+		for i := 0; i < 200; i++ {
+			password, err := Generate(tt.opts)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if len(password) != tt.opts.Length {
+				t.Fatalf("%s: %q has length %d, want %d", tt.name, password, len(password), tt.opts.Length)
+			}
+			for _, c := range password {
+				if !strings.ContainsRune(pool, c) {
+					t.Fatalf("%s: %q contains %q from a disabled set", tt.name, password, c)
+				}
+				if tt.opts.ExcludeAmbiguous && strings.ContainsRune(ambiguous, c) {
+					t.Fatalf("%s: %q contains look-alike %q", tt.name, password, c)
+				}
+			}
+			for _, set := range sets {
+				if !strings.ContainsAny(password, set) {
+					t.Fatalf("%s: %q has nothing from %q", tt.name, password, set)
+				}
+			}
+		}
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	if n := len(wordlist()); n != 7776 {
+		t.Fatalf("wordlist has %d words, want 7776", n)
+	}
+	words := make(map[string]bool)
+	for _, word := range wordlist() {
+		words[word] = true
+	}
+
+	opts := Options{Mode: ModePassphrase, Words: 6, Separator: "."}
+	passphrase, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(passphrase, ".")
+	if len(parts) != opts.Words {
+		t.Fatalf("%q has %d words, want %d", passphrase, len(parts), opts.Words)
+	}
+	for _, part := range parts {
+		if !words[part] {
+			t.Errorf("%q is not in the wordlist", part)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := map[string]Options{
+		"unknown mode":   {Mode: "pin", Length: 20, Lowercase: true},
+		"too short":      {Mode: ModePassword, Length: MinLength - 1, Lowercase: true},
+		"too long":       {Mode: ModePassword, Length: MaxLength + 1, Lowercase: true},
+		"no sets":        {Mode: ModePassword, Length: 20},
+		"too few words":  {Mode: ModePassphrase, Words: MinWords - 1},
+		"too many words": {Mode: ModePassphrase, Words: MaxWords + 1},
+	}
+	for name, opts := range invalid {
+		if _, err := Generate(opts); err == nil {
+			t.Errorf("%s: Generate succeeded", name)
+		}
+	}
+	if err := (Options{Mode: ModePassword, Length: 20}).Validate(); err != ErrNoCharacters {
+		t.Errorf("Validate with no sets = %v, want ErrNoCharacters", err)
+	}
+	if err := DefaultOptions.Validate(); err != nil {
+		t.Errorf("DefaultOptions are invalid: %v", err)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		opts Options
+		bits float64
+	}{
+		{Options{Mode: ModePassword, Length: 10, Digits: true}, 10 * math.Log2(10)},
+		{Options{Mode: ModePassword, Length: 20, Lowercase: true, Uppercase: true}, 20 * math.Log2(52)},
+		// Without I, l, O and o
+		{Options{Mode: ModePassword, Length: 20, Lowercase: true, Uppercase: true, ExcludeAmbiguous: true}, 20 * math.Log2(48)},
+		{Options{Mode: ModePassphrase, Words: 6}, 6 * math.Log2(7776)},
+		{Options{Mode: ModePassword, Length: 20}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.Entropy(); math.Abs(got-tt.bits) > 1e-9 {
+			t.Errorf("Entropy(%s) = %.2f, want %.2f", tt.opts.Describe(), got, tt.bits)
+		}
+	}
+}
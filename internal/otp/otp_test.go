@@ -0,0 +1,151 @@
+package otp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// RFC 4226 appendix D
+func TestHOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		if got := HOTP(secret, uint64(counter), 6, "SHA1"); got != code {
+			t.Errorf("HOTP(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+// RFC 6238 appendix B
+func TestTOTP(t *testing.T) {
+	secrets := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		time      int64
+		algorithm string
+		code      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+	for _, tt := range tests {
+		key := &Key{Type: TypeTOTP, Secret: secrets[tt.algorithm], Algorithm: tt.algorithm, Digits: 8, Period: 30}
+		if got := key.Code(time.Unix(tt.time, 0)); got != tt.code {
+			t.Errorf("TOTP %s at %d = %s, want %s", tt.algorithm, tt.time, got, tt.code)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Key
+	}{
+		{
+			"JBSW Y3DP-EHPK 3PXP",
+			Key{Type: TypeTOTP, Algorithm: "SHA1", Digits: 6, Period: 30},
+		},
+		{
+			"jbswy3dpehpk3pxp",
+			Key{Type: TypeTOTP, Algorithm: "SHA1", Digits: 6, Period: 30},
+		},
+		{
+			"otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example",
+			Key{Type: TypeTOTP, Issuer: "Example", Account: "alice@example.com", Algorithm: "SHA1", Digits: 6, Period: 30},
+		},
+		{
+			"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=sha256&digits=8&period=60",
+			Key{Type: TypeTOTP, Account: "alice", Algorithm: "SHA256", Digits: 8, Period: 60},
+		},
+		{
+			"otpauth://hotp/Example:bob?secret=JBSWY3DPEHPK3PXP&counter=42",
+			Key{Type: TypeHOTP, Issuer: "Example", Account: "bob", Algorithm: "SHA1", Digits: 6, Period: 30, Counter: 42},
+		},
+	}
+	secret := "Hello!\xde\xad\xbe\xef"
+	for _, tt := range tests {
+		key, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if string(key.Secret) != secret {
+			t.Errorf("Parse(%q) secret = %q, want %q", tt.input, key.Secret, secret)
+		}
+		key.Secret = nil
+		if !reflect.DeepEqual(*key, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.input, *key, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"not base32!",
+		"otpauth://totp/alice",
+		"otpauth://push/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=-1",
+	} {
+		if _, err := Parse(input); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidKey", input, err)
+		}
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	for _, key := range []Key{
+		{Type: TypeTOTP, Issuer: "Example", Account: "alice@example.com", Secret: []byte("12345678901234567890"), Algorithm: "SHA512", Digits: 8, Period: 60},
+		{Type: TypeHOTP, Account: "bob", Secret: []byte("12345678901234567890"), Algorithm: "SHA1", Digits: 6, Period: 30, Counter: 7},
+	} {
+		parsed, err := Parse(key.URI())
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", key.URI(), err)
+		}
+		if string(parsed.Secret) != string(key.Secret) {
+			t.Errorf("round trip of %q changed the secret", key.URI())
+		}
+		parsed.Secret, key.Secret = nil, nil
+		if !reflect.DeepEqual(*parsed, key) {
+			t.Errorf("round trip = %+v, want %+v", *parsed, key)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	key := &Key{Type: TypeTOTP, Period: 30}
+	if got := key.Remaining(time.Unix(61, 0)); got != 29*time.Second {
+		t.Errorf("Remaining at 61s = %s, want 29s", got)
+	}
+	hotp := &Key{Type: TypeHOTP, Period: 30}
+	if got := hotp.Remaining(time.Unix(61, 0)); got != 0 {
+		t.Errorf("HOTP Remaining = %s, want 0", got)
+	}
+}
@@ -0,0 +1,56 @@
+package secure
+
+// Buffer holds secret bytes outside the Go heap. On platforms that support
+// it the memory is locked against swapping and surrounded by inaccessible
+// guard pages, so overruns fault instead of leaking neighbouring data.
+type Buffer struct {
+	mem  []byte // whole allocation, including guard pages
+	data []byte // usable region handed out by Bytes
+}
+
+// FromBytes copies b into a new Buffer and wipes b
+func FromBytes(b []byte) (*Buffer, error) {
+	buf, err := New(len(b))
+	if err != nil {
+		Wipe(b)
+		return nil, err
+	}
+	copy(buf.data, b)
+	Wipe(b)
+	return buf, nil
+}
+
+// Bytes returns the buffer contents. The slice aliases the protected memory
+// and must not be used after Destroy.
+func (b *Buffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.data
+}
+
+// Len returns the size of the buffer contents
+func (b *Buffer) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.data)
+}
+
+// Destroy wipes the buffer and releases its memory. It is safe to call more than once.
+func (b *Buffer) Destroy() {
+	if b == nil || b.mem == nil {
+		return
+	}
+	Wipe(b.data)
+	free(b.mem)
+	b.mem = nil
+	b.data = nil
+}
+
+// Wipe overwrites b with zeros
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package secure
+
+// New allocates a zeroed Buffer of the given size. Memory locking and guard
+// pages are not available on this platform, so it is an ordinary allocation.
+func New(size int) (*Buffer, error) {
+	mem := make([]byte, size)
+	return &Buffer{mem: mem, data: mem}, nil
+}
+
+// free releases memory returned by New
+func free(mem []byte) {}
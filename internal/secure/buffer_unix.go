@@ -0,0 +1,47 @@
+//go:build unix
+
+package secure
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// New allocates a zeroed Buffer of the given size in mlock'd memory with a
+// guard page on either side
+func New(size int) (*Buffer, error) {
+	pageSize := os.Getpagesize()
+	dataPages := max(1, (size+pageSize-1)/pageSize)
+	total := (dataPages + 2) * pageSize
+
+	mem, err := unix.Mmap(-1, 0, total, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guard pages before and after the data pages
+	if err := unix.Mprotect(mem[:pageSize], unix.PROT_NONE); err != nil {
+		unix.Munmap(mem)
+		return nil, err
+	}
+	if err := unix.Mprotect(mem[total-pageSize:], unix.PROT_NONE); err != nil {
+		unix.Munmap(mem)
+		return nil, err
+	}
+
+	inner := mem[pageSize : total-pageSize]
+
+	// Locking can fail under a low RLIMIT_MEMLOCK; the guard pages still apply
+	_ = unix.Mlock(inner)
+
+	// Place data at the end of the region so an overrun hits the trailing guard page
+	return &Buffer{mem: mem, data: inner[len(inner)-size:]}, nil
+}
+
+// free unlocks and unmaps memory returned by New
+func free(mem []byte) {
+	pageSize := os.Getpagesize()
+	_ = unix.Munlock(mem[pageSize : len(mem)-pageSize])
+	_ = unix.Munmap(mem)
+}
@@ -0,0 +1,213 @@
+// Package sshagent serves the SSH keys stored in a lockin vault over the
+// ssh-agent protocol. Keys are read from the vault on every request, so they
+// stop being available as soon as the vault is locked.
+package sshagent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"lockin/internal/store"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// ErrReadOnly is returned for requests that would change the keys; they
+	// are managed in the vault instead
+	ErrReadOnly = errors.New("keys are managed in the lockin vault")
+
+	// ErrDenied is returned when a signature request is not confirmed
+	ErrDenied = errors.New("use of key was denied")
+
+	errKeyNotFound = errors.New("key not found")
+)
+
+// ConfirmFunc asks whether the key stored under name may sign a request
+type ConfirmFunc func(name string) bool
+
+// Agent implements agent.ExtendedAgent on top of a vault. Requests are
+// handled one at a time so confirmation prompts never overlap.
+type Agent struct {
+	vault   *store.FileVault
+	confirm ConfirmFunc
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+// vaultKey is an SSH key entry with its parsed private key
+type vaultKey struct {
+	name   string
+	signer ssh.Signer
+}
+
+// New creates an agent serving the SSH keys in vault. confirm is asked before
+// every signature; nil signs without asking.
+func New(vault *store.FileVault, confirm ConfirmFunc) *Agent {
+	return &Agent{vault: vault, confirm: confirm, lastUsed: time.Now()}
+}
+
+// Serve accepts connections on l until it is closed
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := agent.ServeAgent(a, conn); err != nil && err != io.EOF {
+				store.LogError("ssh-agent connection failed: %v", err)
+			}
+		}()
+	}
+}
+
+// LastUsed returns when a client last listed keys or asked for a signature
+func (a *Agent) LastUsed() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lastUsed
+}
+
+// LockVault locks the vault once any request in progress has finished
+func (a *Agent) LockVault() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.vault.Lock()
+}
+
+// keys loads every SSH key from the vault. Keys that fail to parse are
+// logged and skipped.
+func (a *Agent) keys() ([]vaultKey, error) {
+	a.lastUsed = time.Now()
+
+	entries, err := a.vault.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []vaultKey
+	for _, e := range entries {
+		if e.Type != store.TypeSSHKey {
+			continue
+		}
+		signer, err := a.vault.SSHSigner(e.ID)
+		if err != nil {
+			store.LogError("Failed to load SSH key %d: %v", e.ID, err)
+			continue
+		}
+		keys = append(keys, vaultKey{name: e.Name, signer: signer})
+	}
+	return keys, nil
+}
+
+// List returns the public keys of every SSH key in the vault
+func (a *Agent) List() ([]*agent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*agent.Key, len(keys))
+	for i, k := range keys {
+		pub := k.signer.PublicKey()
+		list[i] = &agent.Key{Format: pub.Type(), Blob: pub.Marshal(), Comment: k.name}
+	}
+	return list, nil
+}
+
+// Sign signs data with the vault key matching key
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs data with the vault key matching key, once confirmed.
+// The flags select SHA-2 signatures for RSA keys.
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := key.Marshal()
+	for _, k := range keys {
+		if !bytes.Equal(k.signer.PublicKey().Marshal(), wanted) {
+			continue
+		}
+
+		if a.confirm != nil && !a.confirm(k.name) {
+			store.LogInfo("ssh-agent: use of key '%s' denied", k.name)
+			return nil, ErrDenied
+		}
+		store.LogInfo("ssh-agent: signing with key '%s'", k.name)
+
+		if flags == 0 {
+			return k.signer.Sign(rand.Reader, data)
+		}
+		algorithmSigner, ok := k.signer.(ssh.AlgorithmSigner)
+		if !ok {
+			return nil, fmt.Errorf("key '%s' does not support signature flags", k.name)
+		}
+		switch flags {
+		case agent.SignatureFlagRsaSha256:
+			return algorithmSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA256)
+		case agent.SignatureFlagRsaSha512:
+			return algorithmSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA512)
+		default:
+			return nil, fmt.Errorf("unsupported signature flags: %d", flags)
+		}
+	}
+	return nil, errKeyNotFound
+}
+
+// Signers is not supported; private keys never leave the agent
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return nil, ErrReadOnly
+}
+
+// Add is not supported; import keys into the vault instead
+func (a *Agent) Add(key agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+// Remove is not supported; delete keys from the vault instead
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	return ErrReadOnly
+}
+
+// RemoveAll is not supported; delete keys from the vault instead
+func (a *Agent) RemoveAll() error {
+	return ErrReadOnly
+}
+
+// Lock is not supported; the agent follows the vault's lock
+func (a *Agent) Lock(passphrase []byte) error {
+	return ErrReadOnly
+}
+
+// Unlock is not supported; the agent follows the vault's lock
+func (a *Agent) Unlock(passphrase []byte) error {
+	return ErrReadOnly
+}
+
+// Extension reports that no extensions are supported
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"lockin/internal/secure"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Table name used in ciphertext associated data
+const tableAttachments = "attachments"
+
+// Attachments are stored inside the vault database, so they are limited in
+// size to keep syncing and backups of credentials.db fast
+const (
+	MaxAttachmentSize  = 1 << 20  // per file
+	MaxAttachmentTotal = 16 << 20 // across the vault
+)
+
+var (
+	ErrAttachmentTooLarge = errors.New("attachment is larger than 1 MiB")
+	ErrAttachmentQuota    = errors.New("attachments would exceed the 16 MiB vault limit")
+)
+
+// Attachment is a small file stored encrypted with an entry. The contents are
+// only decrypted on demand by AttachmentData and ExportAttachment.
+type Attachment struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Attachments returns an entry's attachments, oldest first
+func (v *FileVault) Attachments(id int64) ([]Attachment, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	rows, err := v.db.Query("SELECT id, name, size, created_at FROM attachments WHERE credential_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		var encName string
+		if err := rows.Scan(&a.ID, &encName, &a.Size, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if a.Name, err = v.decryptField(tableAttachments, a.ID, "name", encName); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// AttachFile reads the file at path and attaches it to an entry under its base name
+func (v *FileVault) AttachFile(id int64, path string) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return result, err
+	}
+	if !info.Mode().IsRegular() {
+		return result, errors.New("not a regular file")
+	}
+	if info.Size() > MaxAttachmentSize {
+		return result, ErrAttachmentTooLarge
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, err
+	}
+	defer secure.Wipe(data)
+
+	return v.AddAttachment(id, filepath.Base(path), data)
+}
+
+// AddAttachment stores data encrypted as an attachment of an entry
+func (v *FileVault) AddAttachment(id int64, name string, data []byte) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	if v.IsLocked() {
+		return result, ErrVaultLocked
+	}
+	if len(data) > MaxAttachmentSize {
+		return result, ErrAttachmentTooLarge
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM credentials WHERE id = ? AND deleted_at IS NULL", id).Scan(&count); err != nil {
+		return result, err
+	}
+	if count == 0 {
+		return result, ErrEntryNotFound
+	}
+
+	var total int64
+	if err := tx.QueryRow("SELECT COALESCE(SUM(size), 0) FROM attachments").Scan(&total); err != nil {
+		return result, err
+	}
+	if total+int64(len(data)) > MaxAttachmentTotal {
+		return result, ErrAttachmentQuota
+	}
+
+	// Ciphertexts are bound to their row id, so insert first and encrypt after
+	res, err := tx.Exec(`
+		INSERT INTO attachments (credential_id, name, size, data, created_at)
+		VALUES (?, '', ?, '', ?)
+	`, id, len(data), time.Now().Unix())
+	if err != nil {
+		return result, err
+	}
+	attachmentID, err := res.LastInsertId()
+	if err != nil {
+		return result, err
+	}
+
+	key := v.getMasterKey()
+	if key == nil {
+		return result, ErrVaultLocked
+	}
+	encName, err := v.encryptField(tableAttachments, attachmentID, "name", name)
+	if err != nil {
+		return result, err
+	}
+	encData, err := sealWithKey(key, data, fieldAD(v.vaultID, tableAttachments, attachmentID, "data"))
+	if err != nil {
+		return result, err
+	}
+	if _, err := tx.Exec("UPDATE attachments SET name = ?, data = ? WHERE id = ?", encName, []byte(encData), attachmentID); err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	result.SyncError = v.Sync()
+	return result, nil
+}
+
+// AttachmentData decrypts an attachment's contents into locked memory. The
+// caller must Destroy the returned buffer once done with it.
+func (v *FileVault) AttachmentData(attachmentID int64) (*secure.Buffer, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	var encData []byte
+	err := v.db.QueryRow("SELECT data FROM attachments WHERE id = ?", attachmentID).Scan(&encData)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v.decryptSecret(tableAttachments, attachmentID, "data", string(encData))
+}
+
+// ExportAttachment writes an attachment's contents to a new file at path,
+// readable only by the current user. Existing files are never overwritten.
+func (v *FileVault) ExportAttachment(attachmentID int64, path string) error {
+	data, err := v.AttachmentData(attachmentID)
+	if err != nil {
+		return err
+	}
+	defer data.Destroy()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data.Bytes()); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// DeleteAttachment removes an attachment from its entry
+func (v *FileVault) DeleteAttachment(attachmentID int64) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	if v.IsLocked() {
+		return result, ErrVaultLocked
+	}
+
+	res, err := v.db.Exec("DELETE FROM attachments WHERE id = ?", attachmentID)
+	if err != nil {
+		return result, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return result, err
+	}
+	if rows == 0 {
+		return result, ErrEntryNotFound
+	}
+
+	result.SyncError = v.Sync()
+	return result, nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"lockin/internal/breach"
+	"strconv"
+	"time"
+)
+
+// Table name used in ciphertext associated data
+const tableBreaches = "breaches"
+
+// Breach is the result of the last lookup of an entry's password in the
+// Pwned Passwords list. The count is stored encrypted, as it singles out
+// entries with weak passwords.
+type Breach struct {
+	Count     int   `json:"count"` // times the password was seen in breaches; 0 if never
+	CheckedAt int64 `json:"checked_at"`
+}
+
+// Breaches returns the stored breach check of every entry that has one, by entry ID
+func (v *FileVault) Breaches() (map[int64]Breach, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	rows, err := v.db.Query("SELECT credential_id, count, checked_at FROM breaches")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breaches := make(map[int64]Breach)
+	for rows.Next() {
+		var id int64
+		var encCount string
+		var b Breach
+		if err := rows.Scan(&id, &encCount, &b.CheckedAt); err != nil {
+			return nil, err
+		}
+		if b.Count, err = v.decryptBreachCount(id, encCount); err != nil {
+			return nil, err
+		}
+		breaches[id] = b
+	}
+	return breaches, rows.Err()
+}
+
+// EntryBreach returns the stored breach check of an entry, or nil if its
+// password has not been checked
+func (v *FileVault) EntryBreach(id int64) (*Breach, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	var encCount string
+	var b Breach
+	err := v.db.QueryRow("SELECT count, checked_at FROM breaches WHERE credential_id = ?", id).Scan(&encCount, &b.CheckedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.Count, err = v.decryptBreachCount(id, encCount); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// CheckBreaches looks up the password of every entry with a login in list
+// and stores the results, replacing all earlier ones
+func (v *FileVault) CheckBreaches(list *breach.List) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	entries, err := v.List()
+	if err != nil {
+		return result, err
+	}
+
+	// Look every password up before writing, so the file is read outside the transaction
+	counts := make(map[int64]int)
+	for _, entry := range entries {
+		if !entry.Type.HasLogin() {
+			continue
+		}
+		password, err := v.Password(entry.ID)
+		if err != nil {
+			return result, err
+		}
+		if password.Len() > 0 {
+			counts[entry.ID], err = list.Count(password.Bytes())
+		}
+		password.Destroy()
+		if err != nil {
+			return result, err
+		}
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM breaches WHERE credential_id IN (SELECT id FROM credentials WHERE deleted_at IS NULL)"); err != nil {
+		return result, err
+	}
+	now := time.Now().Unix()
+	for id, count := range counts {
+		if err := v.saveBreach(tx, id, count, now); err != nil {
+			return result, err
+		}
+	}
+
+	err = tx.Commit()
+	if err == nil {
+		result.SyncError = v.Sync()
+	}
+	return result, err
+}
+
+// openBreachList opens the configured Pwned Passwords list; tests replace it
+var openBreachList = breach.Open
+
+// lookupBreach looks up an entry's new password in the configured list,
+// before the transaction that saves it is started. It reports false when
+// there is nothing to store: no list is set, the entry has no password, or
+// the lookup failed, in which case the error is returned for the caller to
+// log once the entry's id is known.
+func lookupBreach(entry Entry) (int, bool, error) {
+	path := BreachListPath()
+	if path == "" || !entry.Type.HasLogin() || entry.Password == "" {
+		return 0, false, nil
+	}
+
+	list, err := openBreachList(path)
+	if err != nil {
+		return 0, false, fmt.Errorf("opening breach list: %w", err)
+	}
+	defer list.Close()
+
+	count, err := list.Count([]byte(entry.Password))
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+// replaceBreach replaces the stored breach check of an entry whose password
+// changed with the result of lookupBreach. Without a result the old one is
+// only dropped, and the entry waits for the next full check.
+func (v *FileVault) replaceBreach(tx *sql.Tx, id int64, count int, found bool) error {
+	if _, err := tx.Exec("DELETE FROM breaches WHERE credential_id = ?", id); err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	return v.saveBreach(tx, id, count, time.Now().Unix())
+}
+
+// saveBreach stores the breach check of an entry
+func (v *FileVault) saveBreach(tx *sql.Tx, id int64, count int, checkedAt int64) error {
+	encCount, err := v.encryptField(tableBreaches, id, "count", strconv.Itoa(count))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO breaches (credential_id, count, checked_at) VALUES (?, ?, ?)", id, encCount, checkedAt)
+	return err
+}
+
+// decryptBreachCount decrypts the stored count of an entry's breach check
+func (v *FileVault) decryptBreachCount(id int64, encCount string) (int, error) {
+	count, err := v.decryptField(tableBreaches, id, "count", encCount)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(count)
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"lockin/internal/breach"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeBreachList writes a Pwned Passwords list holding the given passwords and counts
+func writeBreachList(t *testing.T, counts map[string]int) string {
+	t.Helper()
+	var lines []string
+	for password, count := range counts {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d\r\n", strings.ToUpper(hex.EncodeToString(sum[:])), count))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBreaches(t *testing.T) {
+	v := newTestVault(t, "password")
+	path := writeBreachList(t, map[string]int{"hunter2": 17043, "123456": 37359195, "letmein": 1})
+	if _, err := v.Add(Entry{Name: "Email", Username: "bob", Password: "correct horse battery staple"}); err != nil {
+		t.Fatal(err)
+	}
+	github, _ := v.GetByName("GitHub")
+	email, _ := v.GetByName("Email")
+
+	list, err := breach.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+	if _, err := v.CheckBreaches(list); err != nil {
+		t.Fatal(err)
+	}
+	breaches, err := v.Breaches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breaches) != 2 || breaches[github.ID].Count != 17043 || breaches[email.ID].Count != 0 {
+		t.Errorf("Breaches = %+v", breaches)
+	}
+
+	// Saving an entry checks its password again only when it changed, and only
+	// against the configured list
+	Config.BreachList = path
+	opens := 0
+	openBreachList = func(path string) (*breach.List, error) {
+		opens++
+		return breach.Open(path)
+	}
+	t.Cleanup(func() {
+		Config.BreachList = ""
+		openBreachList = breach.Open
+	})
+
+	if _, err := v.db.Exec("UPDATE breaches SET checked_at = 1"); err != nil {
+		t.Fatal(err)
+	}
+	github.Notes = "work account"
+	if _, err := v.Update(*github); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := v.EntryBreach(github.ID); err != nil || b == nil || b.CheckedAt != 1 {
+		t.Errorf("EntryBreach after an update keeping the password = %+v, %v", b, err)
+	}
+	if opens != 0 {
+		t.Errorf("list searched %d times for an unchanged password", opens)
+	}
+
+	email.Password = "letmein"
+	if _, err := v.Update(*email); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := v.EntryBreach(email.ID); err != nil || b == nil || b.Count != 1 || b.CheckedAt == 1 {
+		t.Errorf("EntryBreach after changing the password = %+v, %v", b, err)
+	}
+	if opens != 1 {
+		t.Errorf("list searched %d times for one changed password", opens)
+	}
+
+	if _, err := v.Add(Entry{Name: "Router", Username: "admin", Password: "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	router, _ := v.GetByName("Router")
+	if b, err := v.EntryBreach(router.ID); err != nil || b == nil || b.Count != 37359195 {
+		t.Errorf("EntryBreach of a new entry = %+v, %v", b, err)
+	}
+
+	// A note has no password to check
+	if _, err := v.Add(Entry{Type: TypeNote, Name: "Wifi", Notes: "123456"}); err != nil {
+		t.Fatal(err)
+	}
+	note, _ := v.GetByName("Wifi")
+	if b, err := v.EntryBreach(note.ID); err != nil || b != nil {
+		t.Errorf("EntryBreach of a note = %+v, %v, want nil", b, err)
+	}
+}
@@ -1,8 +1,10 @@
 package store
 
 import (
+	"lockin/internal/generator"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,7 +12,7 @@ import (
 // Database file name
 const DBFileName = "credentials.db"
 
-// SMBConfig holds SMB sync configuration
+// config holds the settings loaded from config.yaml
 type config struct {
 	Enabled  bool   `yaml:"enabled"`
 	Host     string `yaml:"host"`
@@ -18,19 +20,58 @@ type config struct {
 	Share    string `yaml:"share"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
+
+	// Argon2id cost for newly derived keys. KDFMemory is in KiB; a KDFTime
+	// of 0 calibrates the pass count on this machine.
+	KDFMemory  uint32 `yaml:"kdf_memory"`
+	KDFTime    uint32 `yaml:"kdf_time"`
+	KDFThreads uint8  `yaml:"kdf_threads"`
+
+	// Path to a keyfile required, together with the master password, to
+	// unlock vaults created while it was set
+	Keyfile string `yaml:"keyfile"`
+
+	// Minutes of inactivity after which the TUI locks the vault; 0 disables
+	AutoLockMinutes int `yaml:"auto_lock_minutes"`
+
+	// Days deleted entries stay in the trash before being purged; 0 keeps them
+	TrashRetentionDays int `yaml:"trash_retention_days"`
+
+	// Months after which an unchanged password is reported by the audit; 0 disables
+	AuditStaleMonths int `yaml:"audit_stale_months"`
+
+	// Path to a local copy of the Have I Been Pwned Pwned Passwords list
+	// (SHA-1, ordered by hash) to check passwords against; empty disables
+	BreachList string `yaml:"breach_list"`
+
+	// Defaults for generated passwords and passphrases
+	Generator generator.Options `yaml:"generator"`
 }
 
 // Default configuration
 var defaultConfig = config{
-	Enabled:  false,
-	Host:     "",
-	Port:     "445",
-	Share:    "",
-	User:     "",
-	Password: "",
+	Enabled:    false,
+	Host:       "",
+	Port:       "445",
+	Share:      "",
+	User:       "",
+	Password:   "",
+	KDFMemory:  64 * 1024,
+	KDFTime:    0,
+	KDFThreads: 4,
+	Keyfile:    "",
+
+	AutoLockMinutes: 5,
+
+	TrashRetentionDays: 30,
+
+	AuditStaleMonths: 12,
+	BreachList:       "",
+
+	Generator: generator.DefaultOptions,
 }
 
-var SMBConfig config
+var Config config
 
 // LoadConfig loads configuration from the YAML file in .lockin directory
 func LoadConfig() error {
@@ -38,7 +79,7 @@ func LoadConfig() error {
 
 	// If config doesn't exist, create default
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		SMBConfig = defaultConfig
+		Config = defaultConfig
 		return SaveConfig()
 	}
 
@@ -47,7 +88,9 @@ func LoadConfig() error {
 		return err
 	}
 
-	if err := yaml.Unmarshal(data, &SMBConfig); err != nil {
+	// Start from defaults so settings missing from older files keep sane values
+	Config = defaultConfig
+	if err := yaml.Unmarshal(data, &Config); err != nil {
 		return err
 	}
 
@@ -63,7 +106,7 @@ func SaveConfig() error {
 		return err
 	}
 
-	data, err := yaml.Marshal(&SMBConfig)
+	data, err := yaml.Marshal(&Config)
 	if err != nil {
 		return err
 	}
@@ -92,5 +135,36 @@ func GetDBPath() string {
 
 // IsSMBEnabled returns true if SMB sync is enabled in config
 func IsSMBEnabled() bool {
-	return SMBConfig.Enabled
+	return Config.Enabled
+}
+
+// AutoLockTimeout returns the idle time after which the vault should lock, or 0 if disabled
+func AutoLockTimeout() time.Duration {
+	if Config.AutoLockMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(Config.AutoLockMinutes) * time.Minute
+}
+
+// AuditStaleAge returns how long a password can go unchanged before the audit
+// reports it, or 0 if disabled
+func AuditStaleAge() time.Duration {
+	if Config.AuditStaleMonths <= 0 {
+		return 0
+	}
+	return time.Duration(Config.AuditStaleMonths) * 30 * 24 * time.Hour
+}
+
+// BreachListPath returns the configured Pwned Passwords file, or "" if none is set
+func BreachListPath() string {
+	return Config.BreachList
+}
+
+// GeneratorOptions returns the configured password generator defaults,
+// falling back to the built-in defaults if they are invalid
+func GeneratorOptions() generator.Options {
+	if err := Config.Generator.Validate(); err != nil {
+		return generator.DefaultOptions
+	}
+	return Config.Generator
 }
@@ -3,70 +3,303 @@ package store
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"lockin/internal/secure"
+	"strings"
+	"time"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/pbkdf2"
 )
 
-// Salt for key derivation
-var keySalt = []byte{0x59, 0xa8, 0x42, 0x85, 0x8d, 0x95, 0xe1, 0xb9, 0x0e, 0x19, 0x11, 0x17, 0x03, 0x2e, 0x0a, 0x9d}
+// KDF algorithm identifiers stored in vault_meta
+const (
+	kdfPBKDF2   = "pbkdf2-sha256"
+	kdfArgon2id = "argon2id"
+)
+
+// kdfTarget is how long Argon2id calibration aims for a single derivation to take
+const kdfTarget = 500 * time.Millisecond
+
+// maxKDFTime caps the calibrated Argon2id pass count
+const maxKDFTime = 10
+
+// saltSize is the length of the random per-vault salt
+const saltSize = 16
+
+// Salt and iteration count used by vaults created before vault_meta existed
+var legacySalt = []byte{0x59, 0xa8, 0x42, 0x85, 0x8d, 0x95, 0xe1, 0xb9, 0x0e, 0x19, 0x11, 0x17, 0x03, 0x2e, 0x0a, 0x9d}
+
+const legacyIterations = 100000
+
+// Bounds on stored key derivation parameters. Values outside them come from
+// a damaged or tampered vault: too weak to protect it, or costly enough to
+// hang or exhaust memory on unlock.
+const (
+	minPBKDF2Iterations = legacyIterations
+	maxKDFMemory        = 4 * 1024 * 1024 // KiB
+)
+
+// verifierPlaintext was encrypted with the password-derived key by vaults
+// created before envelope encryption, to detect a wrong password
+const verifierPlaintext = "lockin-vault-verifier"
+
+// dataKeySize is the length of the random data-encryption key
+const dataKeySize = 32
+
+// HKDF info strings for the keys behind the name and tag blind indexes
+const (
+	nameIndexInfo = "lockin name index"
+	tagIndexInfo  = "lockin tag index"
+)
+
+// ciphertextV2Prefix marks ciphertexts sealed with associated data. Unprefixed
+// (v1) ciphertexts were sealed without any and are still accepted on read.
+const ciphertextV2Prefix = "v2:"
+
+// vaultIDSize is the length of the random vault identifier
+const vaultIDSize = 16
+
+// kdfParams describes how the master key is derived from the password
+type kdfParams struct {
+	Algorithm string
+	Salt      []byte
+
+	// PBKDF2
+	Iterations int
+
+	// Argon2id
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// legacyKDFParams returns the parameters used by vaults without vault_meta
+func legacyKDFParams() kdfParams {
+	return kdfParams{
+		Algorithm:  kdfPBKDF2,
+		Salt:       legacySalt,
+		Iterations: legacyIterations,
+	}
+}
+
+// validate reports ErrCorruptVault if the parameters are outside the bounds
+// any vault is written with
+func (p kdfParams) validate() error {
+	if len(p.Salt) < saltSize {
+		return fmt.Errorf("%w: %d-byte salt", ErrCorruptVault, len(p.Salt))
+	}
+	switch p.Algorithm {
+	case kdfPBKDF2:
+		if p.Iterations < minPBKDF2Iterations {
+			return fmt.Errorf("%w: %d PBKDF2 iterations", ErrCorruptVault, p.Iterations)
+		}
+	case kdfArgon2id:
+		if p.Time < 1 || p.Threads < 1 || p.Memory > maxKDFMemory {
+			return fmt.Errorf("%w: Argon2id time %d, memory %d KiB, threads %d", ErrCorruptVault, p.Time, p.Memory, p.Threads)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported kdf %q", ErrCorruptVault, p.Algorithm)
+	}
+	return nil
+}
+
+// newKDFParams returns Argon2id parameters from config with a fresh random salt
+func newKDFParams() (kdfParams, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return kdfParams{}, err
+	}
+
+	params := kdfParams{
+		Algorithm: kdfArgon2id,
+		Salt:      salt,
+		Memory:    Config.KDFMemory,
+		Time:      Config.KDFTime,
+		Threads:   Config.KDFThreads,
+	}
+	if params.Memory == 0 {
+		params.Memory = defaultConfig.KDFMemory
+	}
+	if params.Threads == 0 {
+		params.Threads = defaultConfig.KDFThreads
+	}
+	if params.Memory > maxKDFMemory {
+		return kdfParams{}, fmt.Errorf("kdf_memory of %d KiB is above the %d KiB limit", params.Memory, maxKDFMemory)
+	}
+	if params.Time == 0 {
+		params.Time = calibrateArgon2(params.Memory, params.Threads)
+	}
+	return params, nil
+}
+
+// calibrateArgon2 picks the Argon2id pass count that takes roughly kdfTarget
+// on this machine at the given memory and parallelism
+func calibrateArgon2(memory uint32, threads uint8) uint32 {
+	start := time.Now()
+	argon2.IDKey([]byte("calibration"), make([]byte, saltSize), 1, memory, threads, 32)
+	elapsed := time.Since(start)
+
+	passes := uint32(1)
+	if elapsed > 0 {
+		passes = uint32((kdfTarget + elapsed - 1) / elapsed)
+	}
+	passes = max(1, min(passes, maxKDFTime))
+
+	LogInfo("Argon2id calibrated: %d passes (%v per pass at %d KiB)", passes, elapsed, memory)
+	return passes
+}
+
+// deriveKey derives a 32-byte key from the master password using the given parameters
+func deriveKey(password string, params kdfParams) ([]byte, error) {
+	switch params.Algorithm {
+	case kdfArgon2id:
+		return argon2.IDKey([]byte(password), params.Salt, params.Time, params.Memory, params.Threads, 32), nil
+	case kdfPBKDF2:
+		return pbkdf2.Key([]byte(password), params.Salt, params.Iterations, 32, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %s", params.Algorithm)
+	}
+}
+
+// checkVerifier returns ErrInvalidPassword unless verifier was made with key
+func checkVerifier(key []byte, verifier string) error {
+	plaintext, err := decryptWithKey(key, verifier, nil)
+	if err != nil || plaintext != verifierPlaintext {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// newDataKey generates a random data-encryption key
+func newDataKey() ([]byte, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
 
-// XOR mask for obfuscating the master key in memory
-var xorMask = []byte{
-	0x3c, 0x7f, 0x1a, 0x9e, 0x5b, 0xd2, 0x48, 0xe3,
-	0x71, 0x0c, 0x8a, 0xf5, 0x29, 0x64, 0xb7, 0x03,
-	0x3c, 0x7f, 0x1a, 0x9e, 0x5b, 0xd2, 0x48, 0xe3,
-	0x71, 0x0c, 0x8a, 0xf5, 0x29, 0x64, 0xb7, 0x03,
+// wrapKey encrypts a data key with a key-encryption key
+func wrapKey(kek, dataKey []byte) (string, error) {
+	return sealWithKey(kek, dataKey, []byte(metaWrappedKey))
 }
 
-// deriveKey derives a 32-byte key from the master password using PBKDF2
-func deriveKey(password string) []byte {
-	return pbkdf2.Key([]byte(password), keySalt, 100000, 32, sha256.New)
+// unwrapKey decrypts a wrapped data key, returning ErrInvalidPassword if kek is wrong
+func unwrapKey(kek []byte, wrapped string) ([]byte, error) {
+	plaintext, err := openWithKey(kek, wrapped, []byte(metaWrappedKey))
+	if err != nil || len(plaintext) != dataKeySize {
+		return nil, ErrInvalidPassword
+	}
+	return plaintext, nil
 }
 
-// xorBytes applies XOR operation to obfuscate/deobfuscate data
-func xorBytes(data, mask []byte) []byte {
-	result := make([]byte, len(data))
-	for i := range data {
-		result[i] = data[i] ^ mask[i%len(mask)]
+// newVaultID generates a random identifier that binds ciphertexts to this vault
+func newVaultID() (string, error) {
+	id := make([]byte, vaultIDSize)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", err
 	}
-	return result
+	return hex.EncodeToString(id), nil
+}
+
+// fieldAD builds the associated data binding a ciphertext to the vault, table,
+// row and column it is stored in, so it cannot be moved elsewhere undetected
+func fieldAD(vaultID, table string, rowID int64, column string) []byte {
+	return []byte(fmt.Sprintf("%s/%s/%d/%s", vaultID, table, rowID, column))
 }
 
-// getMasterKey retrieves and deobfuscates the master key
+// blindIndex returns a keyed HMAC of the lowercased name, with the key derived
+// from the data key under info, so names can be matched for equality without
+// storing them in plaintext
+func blindIndex(dataKey []byte, info, name string) (string, error) {
+	indexKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, dataKey, nil, []byte(info)), indexKey); err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte(strings.ToLower(name)))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// nameIndex returns the blind index of name under the vault's data key
+func (v *FileVault) nameIndex(name string) (string, error) {
+	key := v.getMasterKey()
+	if key == nil {
+		return "", ErrVaultLocked
+	}
+	return blindIndex(key, nameIndexInfo, name)
+}
+
+// tagIndex returns the blind index of a tag name under the vault's data key
+func (v *FileVault) tagIndex(tag string) (string, error) {
+	key := v.getMasterKey()
+	if key == nil {
+		return "", ErrVaultLocked
+	}
+	return blindIndex(key, tagIndexInfo, tag)
+}
+
+// getMasterKey returns the data key held in locked memory, or nil when locked
 func (v *FileVault) getMasterKey() []byte {
-	if v.obfuscatedKey == nil {
+	if v.masterKey == nil {
 		return nil
 	}
-	return xorBytes(v.obfuscatedKey, xorMask)
+	return v.masterKey.Bytes()
 }
 
-// setMasterKey obfuscates and stores the master key
-func (v *FileVault) setMasterKey(key []byte) {
-	v.obfuscatedKey = xorBytes(key, xorMask)
+// setMasterKey moves the data key into locked memory and wipes the source slice
+func (v *FileVault) setMasterKey(key []byte) error {
+	buf, err := secure.FromBytes(key)
+	if err != nil {
+		return err
+	}
+	v.clearMasterKey()
+	v.masterKey = buf
+	return nil
 }
 
-// clearMasterKey securely clears the master key from memory
+// clearMasterKey wipes and releases the data key
 func (v *FileVault) clearMasterKey() {
-	if v.obfuscatedKey != nil {
-		for i := range v.obfuscatedKey {
-			v.obfuscatedKey[i] = 0
-		}
-		v.obfuscatedKey = nil
+	v.masterKey.Destroy()
+	v.masterKey = nil
+}
+
+// encryptField encrypts a value stored in the given table, row and column
+func (v *FileVault) encryptField(table string, rowID int64, column, plaintext string) (string, error) {
+	key := v.getMasterKey()
+	if key == nil {
+		return "", ErrVaultLocked
 	}
+	return encryptWithKey(key, plaintext, fieldAD(v.vaultID, table, rowID, column))
 }
 
-// encrypt encrypts plaintext using AES-GCM
-func (v *FileVault) encrypt(plaintext string) (string, error) {
+// decryptField decrypts a value stored in the given table, row and column
+func (v *FileVault) decryptField(table string, rowID int64, column, ciphertext string) (string, error) {
 	key := v.getMasterKey()
 	if key == nil {
 		return "", ErrVaultLocked
 	}
+	return decryptWithKey(key, ciphertext, fieldAD(v.vaultID, table, rowID, column))
+}
+
+// encryptWithKey encrypts plaintext using AES-GCM with associated data,
+// producing a v2 ciphertext
+func encryptWithKey(key []byte, plaintext string, ad []byte) (string, error) {
+	return sealWithKey(key, []byte(plaintext), ad)
+}
 
+// sealWithKey is encryptWithKey taking the plaintext as bytes
+func sealWithKey(key, plaintext, ad []byte) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
@@ -82,42 +315,61 @@ func (v *FileVault) encrypt(plaintext string) (string, error) {
 		return "", err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	ciphertext := gcm.Seal(nonce, nonce, plaintext, ad)
+	return ciphertextV2Prefix + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// decrypt decrypts ciphertext using AES-GCM
-func (v *FileVault) decrypt(ciphertext string) (string, error) {
+// decryptSecret decrypts a value stored in the given table, row and column
+// straight into locked memory, for secrets that should not live on the Go heap
+func (v *FileVault) decryptSecret(table string, rowID int64, column, ciphertext string) (*secure.Buffer, error) {
 	key := v.getMasterKey()
 	if key == nil {
-		return "", ErrVaultLocked
+		return nil, ErrVaultLocked
+	}
+	plaintext, err := openWithKey(key, ciphertext, fieldAD(v.vaultID, table, rowID, column))
+	if err != nil {
+		return nil, err
 	}
+	return secure.FromBytes(plaintext)
+}
 
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+// decryptWithKey decrypts ciphertext using AES-GCM. v2 ciphertexts must match
+// ad; v1 ciphertexts carry no associated data and ignore it.
+func decryptWithKey(key []byte, ciphertext string, ad []byte) (string, error) {
+	plaintext, err := openWithKey(key, ciphertext, ad)
 	if err != nil {
 		return "", err
 	}
+	return string(plaintext), nil
+}
+
+// openWithKey is decryptWithKey returning the plaintext bytes
+func openWithKey(key []byte, ciphertext string, ad []byte) ([]byte, error) {
+	encoded, isV2 := strings.CutPrefix(ciphertext, ciphertextV2Prefix)
+	if !isV2 {
+		ad = nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
-		return "", errors.New("ciphertext too short")
+		return nil, errors.New("ciphertext too short")
 	}
 
 	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
+	return gcm.Open(nil, nonce, ciphertextBytes, ad)
 }
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newMetaDB opens an empty database holding only the vault_meta table
+func newMetaDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), DBFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE vault_meta (key TEXT PRIMARY KEY, value TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDeriveKey(t *testing.T) {
+	// RFC 7914 section 11, truncated to the 32 bytes deriveKey returns
+	key, err := deriveKey("passwd", kdfParams{Algorithm: kdfPBKDF2, Salt: []byte("salt"), Iterations: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(key), "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"; got != want {
+		t.Errorf("PBKDF2 key = %s, want %s", got, want)
+	}
+
+	params := kdfParams{Algorithm: kdfArgon2id, Salt: make([]byte, saltSize), Memory: 1024, Time: 1, Threads: 1}
+	key, err = deriveKey("password", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Fatalf("Argon2id key is %d bytes, want 32", len(key))
+	}
+
+	// Every parameter must feed into the key
+	tests := []struct {
+		name   string
+		change func(*kdfParams)
+	}{
+		{"salt", func(p *kdfParams) { p.Salt = bytes.Repeat([]byte{1}, saltSize) }},
+		{"memory", func(p *kdfParams) { p.Memory = 2048 }},
+		{"time", func(p *kdfParams) { p.Time = 2 }},
+		{"threads", func(p *kdfParams) { p.Threads = 2 }},
+	}
+	for _, tt := range tests {
+		changed := params
+		tt.change(&changed)
+		other, err := deriveKey("password", changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(key, other) {
+			t.Errorf("changing %s did not change the key", tt.name)
+		}
+	}
+
+	if _, err := deriveKey("password", kdfParams{Algorithm: "scrypt"}); err == nil {
+		t.Error("deriveKey accepted an unknown algorithm")
+	}
+}
+
+func TestKDFParamsRoundTrip(t *testing.T) {
+	db := newMetaDB(t)
+
+	if _, found, err := loadKDFParams(db); found || err != nil {
+		t.Fatalf("loadKDFParams on a new vault = %v, %v, want not found", found, err)
+	}
+
+	// Saving one algorithm after the other must not leave stale parameters behind
+	for _, params := range []kdfParams{
+		{Algorithm: kdfArgon2id, Salt: []byte("0123456789abcdef"), Memory: 65536, Time: 3, Threads: 4},
+		legacyKDFParams(),
+		{Algorithm: kdfArgon2id, Salt: []byte("fedcba9876543210"), Memory: 1024, Time: 1, Threads: 1},
+	} {
+		if err := saveKDFParams(db, params); err != nil {
+			t.Fatal(err)
+		}
+		loaded, found, err := loadKDFParams(db)
+		if err != nil || !found {
+			t.Fatalf("loadKDFParams = %v, %v", found, err)
+		}
+		if !reflect.DeepEqual(loaded, params) {
+			t.Errorf("loaded %+v, saved %+v", loaded, params)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM vault_meta WHERE key = ?", metaKDFIterations).Scan(&count); err != nil || count != 0 {
+		t.Errorf("%d stale PBKDF2 iteration counts left (%v)", count, err)
+	}
+}
+
+// sealV1 encrypts plaintext the way vaults did before associated data was
+// used: AES-GCM with none, and no prefix
+func sealV1(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func TestFieldAD(t *testing.T) {
+	key, _ := newDataKey()
+	ad := fieldAD("vault", tableCredentials, 1, "password")
+
+	v2, err := encryptWithKey(key, "hunter2", ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(v2, ciphertextV2Prefix) {
+		t.Errorf("ciphertext %q lacks the v2 prefix", v2)
+	}
+	if plaintext, err := decryptWithKey(key, v2, ad); err != nil || plaintext != "hunter2" {
+		t.Errorf("decrypt = %q, %v, want hunter2", plaintext, err)
+	}
+	tests := []struct {
+		name string
+		ad   []byte
+	}{
+		{"vault", fieldAD("other", tableCredentials, 1, "password")},
+		{"table", fieldAD("vault", "other", 1, "password")},
+		{"row", fieldAD("vault", tableCredentials, 2, "password")},
+		{"column", fieldAD("vault", tableCredentials, 1, "username")},
+	}
+	for _, tt := range tests {
+		if _, err := decryptWithKey(key, v2, tt.ad); err == nil {
+			t.Errorf("v2 ciphertext decrypted under another %s", tt.name)
+		}
+	}
+
+	// v1 ciphertexts carry no associated data and open under any
+	v1 := sealV1(t, key, "hunter2")
+	if plaintext, err := decryptWithKey(key, v1, ad); err != nil || plaintext != "hunter2" {
+		t.Errorf("decrypt v1 = %q, %v, want hunter2", plaintext, err)
+	}
+	// but cannot be passed off as v2 to skip the check
+	if _, err := decryptWithKey(key, ciphertextV2Prefix+v1, ad); err == nil {
+		t.Error("v1 ciphertext with a v2 prefix decrypted")
+	}
+}
+
+func TestMovedCiphertext(t *testing.T) {
+	v := newTestVault(t, "password")
+	if _, err := v.Add(Entry{Name: "Email", Username: "bob", Password: "pa55word"}); err != nil {
+		t.Fatal(err)
+	}
+	github, _ := v.GetByName("GitHub")
+	email, _ := v.GetByName("Email")
+
+	// Copying a password into another row, or into another column, must be detected
+	_, err := v.db.Exec("UPDATE credentials SET password = (SELECT password FROM credentials WHERE id = ?) WHERE id = ?", github.ID, email.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get(email.ID); err == nil {
+		t.Error("password moved to another row decrypted")
+	}
+	if _, err := v.db.Exec("UPDATE credentials SET username = password WHERE id = ?", github.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get(github.ID); err == nil {
+		t.Error("password moved to the username column decrypted")
+	}
+}
+
+func TestLoadKDFParamsBounds(t *testing.T) {
+	db := newMetaDB(t)
+	argon := kdfParams{Algorithm: kdfArgon2id, Salt: make([]byte, saltSize), Memory: 1024, Time: 1, Threads: 1}
+
+	tests := []struct {
+		name   string
+		change func(*kdfParams)
+	}{
+		{"zero passes", func(p *kdfParams) { p.Time = 0 }},
+		{"zero threads", func(p *kdfParams) { p.Threads = 0 }},
+		{"too much memory", func(p *kdfParams) { p.Memory = maxKDFMemory + 1 }},
+		{"short salt", func(p *kdfParams) { p.Salt = p.Salt[:saltSize-1] }},
+		{"few iterations", func(p *kdfParams) { *p = legacyKDFParams(); p.Iterations = minPBKDF2Iterations - 1 }},
+		{"unknown algorithm", func(p *kdfParams) { p.Algorithm = "scrypt" }},
+	}
+	for _, tt := range tests {
+		params := argon
+		tt.change(&params)
+		if err := saveKDFParams(db, params); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := loadKDFParams(db); !errors.Is(err, ErrCorruptVault) {
+			t.Errorf("%s: loadKDFParams = %v, want ErrCorruptVault", tt.name, err)
+		}
+	}
+
+	// The limits themselves are allowed
+	for _, params := range []kdfParams{
+		{Algorithm: kdfArgon2id, Salt: argon.Salt, Memory: maxKDFMemory, Time: 1, Threads: 1},
+		legacyKDFParams(),
+	} {
+		if err := saveKDFParams(db, params); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := loadKDFParams(db); err != nil {
+			t.Errorf("loadKDFParams(%+v) = %v", params, err)
+		}
+	}
+}
+
+func TestUnlockCorruptKDFParams(t *testing.T) {
+	v := newTestVault(t, "password")
+	v.Lock()
+
+	if err := setMeta(v.db, metaKDFThreads, "0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Unlock("password"); !errors.Is(err, ErrCorruptVault) {
+		t.Errorf("Unlock = %v, want ErrCorruptVault", err)
+	}
+}
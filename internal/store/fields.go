@@ -0,0 +1,194 @@
+package store
+
+import (
+	"database/sql"
+	"lockin/internal/secure"
+)
+
+// Table name used in ciphertext associated data
+const tableCustomFields = "custom_fields"
+
+// CustomField is an extra named value on an entry, such as a PIN or a
+// security answer. Concealed values are masked in the UI and, like
+// passwords, decrypted only on demand.
+type CustomField struct {
+	ID        int64  `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Concealed bool   `json:"concealed,omitempty"`
+}
+
+// Fields returns an entry's custom fields in order. Concealed values are left
+// empty; use FieldValue to decrypt one.
+func (v *FileVault) Fields(id int64) ([]CustomField, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+	return v.loadFields(v.db, id, false)
+}
+
+// FieldValue decrypts a custom field's value into locked memory. The caller
+// must Destroy the returned buffer once done with it.
+func (v *FileVault) FieldValue(fieldID int64) (*secure.Buffer, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	var encValue string
+	var concealed bool
+	err := v.db.QueryRow("SELECT value, concealed FROM custom_fields WHERE id = ?", fieldID).Scan(&encValue, &concealed)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v.decryptSecret(tableCustomFields, fieldID, valueColumn(concealed), encValue)
+}
+
+// valueColumn names the value column in a field's associated data. The
+// concealed flag is stored in plaintext, so binding it here makes a value
+// whose flag was cleared fail to decrypt rather than show unmasked.
+func valueColumn(concealed bool) string {
+	if concealed {
+		return "value/concealed"
+	}
+	return "value"
+}
+
+// loadFields reads and decrypts an entry's custom fields. Concealed values are
+// only decrypted when withConcealed is set.
+func (v *FileVault) loadFields(q querier, id int64, withConcealed bool) ([]CustomField, error) {
+	rows, err := q.Query(`
+		SELECT id, name, value, concealed FROM custom_fields
+		WHERE credential_id = ? ORDER BY position, id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []CustomField
+	for rows.Next() {
+		var f CustomField
+		var encName, encValue string
+		if err := rows.Scan(&f.ID, &encName, &encValue, &f.Concealed); err != nil {
+			return nil, err
+		}
+		if f.Name, err = v.decryptField(tableCustomFields, f.ID, "name", encName); err != nil {
+			return nil, err
+		}
+		if !f.Concealed || withConcealed {
+			if f.Value, err = v.decryptField(tableCustomFields, f.ID, valueColumn(f.Concealed), encValue); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// saveFields replaces an entry's custom fields with the given ones
+func (v *FileVault) saveFields(tx *sql.Tx, id int64, fields []CustomField) error {
+	if _, err := tx.Exec("DELETE FROM custom_fields WHERE credential_id = ?", id); err != nil {
+		return err
+	}
+
+	for i, f := range fields {
+		// Ciphertexts are bound to their row id, so insert first and encrypt after
+		res, err := tx.Exec(`
+			INSERT INTO custom_fields (credential_id, position, name, value, concealed)
+			VALUES (?, ?, '', '', ?)
+		`, id, i, f.Concealed)
+		if err != nil {
+			return err
+		}
+		fieldID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		encName, err := v.encryptField(tableCustomFields, fieldID, "name", f.Name)
+		if err != nil {
+			return err
+		}
+		encValue, err := v.encryptField(tableCustomFields, fieldID, valueColumn(f.Concealed), f.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE custom_fields SET name = ?, value = ? WHERE id = ?", encName, encValue, fieldID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebindConcealedFields re-encrypts concealed values written before the
+// concealed flag was bound to them, once, after migrateConcealedFields
+// marked the vault
+func (v *FileVault) rebindConcealedFields() error {
+	_, pending, err := getMeta(v.db, metaRebindConcealed)
+	if err != nil || !pending {
+		return err
+	}
+	key := v.getMasterKey()
+	if key == nil {
+		return ErrVaultLocked
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type concealedRow struct {
+		id    int64
+		value string
+	}
+	rows, err := tx.Query("SELECT id, value FROM custom_fields WHERE concealed = 1")
+	if err != nil {
+		return err
+	}
+	var concealed []concealedRow
+	for rows.Next() {
+		var r concealedRow
+		if err := rows.Scan(&r.id, &r.value); err != nil {
+			rows.Close()
+			return err
+		}
+		concealed = append(concealed, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	LogInfo("Binding the concealed flag to %d custom field values", len(concealed))
+	for _, r := range concealed {
+		value, err := openWithKey(key, r.value, fieldAD(v.vaultID, tableCustomFields, r.id, "value"))
+		if err != nil {
+			return err
+		}
+		enc, err := sealWithKey(key, value, fieldAD(v.vaultID, tableCustomFields, r.id, valueColumn(true)))
+		secure.Wipe(value)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE custom_fields SET value = ? WHERE id = ?", enc, r.id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM vault_meta WHERE key = ?", metaRebindConcealed); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := v.Sync(); err != nil {
+		LogError("Sync after binding concealed fields failed: %v", err)
+	}
+	return nil
+}
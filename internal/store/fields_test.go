@@ -0,0 +1,114 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+// addFieldsEntry adds an entry with a concealed and a plain custom field,
+// returning the field IDs in that order
+func addFieldsEntry(t *testing.T, v *FileVault) (int64, int64) {
+	t.Helper()
+	entry := Entry{Name: "Bank", Username: "alice", Fields: []CustomField{
+		{Name: "PIN", Value: "1234", Concealed: true},
+		{Name: "Branch", Value: "High Street"},
+	}}
+	if _, err := v.Add(entry); err != nil {
+		t.Fatal(err)
+	}
+	added, err := v.GetByName("Bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := v.Fields(added.ID)
+	if err != nil || len(fields) != 2 {
+		t.Fatalf("Fields = %+v, %v", fields, err)
+	}
+	if fields[0].Value != "" || fields[1].Value != "High Street" {
+		t.Errorf("Fields = %+v, want the concealed value left out", fields)
+	}
+	return fields[0].ID, fields[1].ID
+}
+
+// checkFieldValue checks that FieldValue decrypts a field to want
+func checkFieldValue(t *testing.T, v *FileVault, id int64, want string) {
+	t.Helper()
+	value, err := v.FieldValue(id)
+	if err != nil {
+		t.Fatalf("FieldValue(%d): %v", id, err)
+	}
+	defer value.Destroy()
+	if string(value.Bytes()) != want {
+		t.Errorf("FieldValue(%d) = %q, want %q", id, value.Bytes(), want)
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	v := newTestVault(t, "password")
+	pin, branch := addFieldsEntry(t, v)
+	checkFieldValue(t, v, pin, "1234")
+	checkFieldValue(t, v, branch, "High Street")
+
+	// Get loads concealed values too, for the edit form
+	entry, err := v.GetByName("Bank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Fields) != 2 || entry.Fields[0].Value != "1234" || !entry.Fields[0].Concealed {
+		t.Errorf("Fields = %+v", entry.Fields)
+	}
+}
+
+func TestConcealedFlagBound(t *testing.T) {
+	v := newTestVault(t, "password")
+	pin, branch := addFieldsEntry(t, v)
+
+	// Clearing or setting the flag outside lockin must not reveal or mask the value
+	if _, err := v.db.Exec("UPDATE custom_fields SET concealed = 1 - concealed"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{pin, branch} {
+		if _, err := v.FieldValue(id); err == nil {
+			t.Errorf("field %d decrypted with its concealed flag flipped", id)
+		}
+	}
+}
+
+func TestRebindConcealedFields(t *testing.T) {
+	v := newTestVault(t, "password")
+	pin, branch := addFieldsEntry(t, v)
+
+	// Put the concealed value back the way schema version 10 wrote it
+	var enc string
+	if err := v.db.QueryRow("SELECT value FROM custom_fields WHERE id = ?", pin).Scan(&enc); err != nil {
+		t.Fatal(err)
+	}
+	key := v.getMasterKey()
+	value, err := openWithKey(key, enc, fieldAD(v.vaultID, tableCustomFields, pin, valueColumn(true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc, err = sealWithKey(key, value, fieldAD(v.vaultID, tableCustomFields, pin, "value")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.db.Exec("UPDATE custom_fields SET value = ? WHERE id = ?", enc, pin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion-1)); err != nil {
+		t.Fatal(err)
+	}
+	v.Close()
+
+	v = openTestVault(t)
+	if _, pending, _ := getMeta(v.db, metaRebindConcealed); !pending {
+		t.Fatal("migration did not mark the concealed fields")
+	}
+	if err := v.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, pending, _ := getMeta(v.db, metaRebindConcealed); pending {
+		t.Error("concealed fields still marked after unlock")
+	}
+	checkFieldValue(t, v, pin, "1234")
+	checkFieldValue(t, v, branch, "High Street")
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"bytes"
+	"database/sql"
+	"lockin/internal/secure"
+)
+
+// Table name used in ciphertext associated data
+const tablePasswordHistory = "password_history"
+
+// PasswordChange is a previous password of an entry, replaced at ChangedAt.
+// The old password itself is decrypted on demand with HistoryPassword.
+type PasswordChange struct {
+	ID        int64 `json:"id"`
+	EntryID   int64 `json:"entry_id"`
+	ChangedAt int64 `json:"changed_at"`
+}
+
+// History returns the previous passwords of an entry, most recent first
+func (v *FileVault) History(id int64) ([]PasswordChange, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	rows, err := v.db.Query(`
+		SELECT id, credential_id, changed_at
+		FROM password_history WHERE credential_id = ?
+		ORDER BY changed_at DESC, id DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []PasswordChange
+	for rows.Next() {
+		var c PasswordChange
+		if err := rows.Scan(&c.ID, &c.EntryID, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// HistoryPassword decrypts a previous password into locked memory. The
+// caller must Destroy the returned buffer once done with it.
+func (v *FileVault) HistoryPassword(changeID int64) (*secure.Buffer, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	var encPassword string
+	err := v.db.QueryRow("SELECT password FROM password_history WHERE id = ?", changeID).Scan(&encPassword)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v.decryptSecret(tablePasswordHistory, changeID, "password", encPassword)
+}
+
+// passwordChanged reports whether newPassword differs from an entry's current password
+func (v *FileVault) passwordChanged(id int64, newPassword string) (bool, error) {
+	current, err := v.Password(id)
+	if err != nil {
+		return false, err
+	}
+	defer current.Destroy()
+	return !bytes.Equal(current.Bytes(), []byte(newPassword)), nil
+}
+
+// recordPasswordChange moves an entry's current password into its history
+// if newPassword differs from it, reporting whether it did
+func (v *FileVault) recordPasswordChange(tx *sql.Tx, id int64, newPassword string, changedAt int64) (bool, error) {
+	key := v.getMasterKey()
+	if key == nil {
+		return false, ErrVaultLocked
+	}
+
+	var encPassword string
+	if err := tx.QueryRow("SELECT password FROM credentials WHERE id = ?", id).Scan(&encPassword); err != nil {
+		return false, err
+	}
+	old, err := openWithKey(key, encPassword, fieldAD(v.vaultID, tableCredentials, id, "password"))
+	if err != nil {
+		return false, err
+	}
+	defer secure.Wipe(old)
+
+	if bytes.Equal(old, []byte(newPassword)) {
+		return false, nil
+	}
+
+	// Ciphertexts are bound to their row id, so insert first and encrypt after
+	res, err := tx.Exec("INSERT INTO password_history (credential_id, password, changed_at) VALUES (?, '', ?)", id, changedAt)
+	if err != nil {
+		return false, err
+	}
+	changeID, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	enc, err := sealWithKey(key, old, fieldAD(v.vaultID, tablePasswordHistory, changeID, "password"))
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec("UPDATE password_history SET password = ? WHERE id = ?", enc, changeID)
+	return true, err
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"lockin/internal/secure"
+	"os"
+)
+
+// keyfileCheckInfo domain-separates the stored keyfile check from the digest
+const keyfileCheckInfo = "lockin keyfile check"
+
+// keyfileOverride is set from the --keyfile flag and takes precedence over config
+var keyfileOverride string
+
+// SetKeyfile sets the keyfile path used instead of the one in config.yaml
+func SetKeyfile(path string) {
+	keyfileOverride = path
+}
+
+// KeyfilePath returns the configured keyfile path, or "" if none is set
+func KeyfilePath() string {
+	if keyfileOverride != "" {
+		return keyfileOverride
+	}
+	return Config.Keyfile
+}
+
+// readKeyfileDigest reads the keyfile at path and returns its SHA-256 digest
+func readKeyfileDigest(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyfileMissing, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	digest := sha256.Sum256(data)
+	return digest[:], nil
+}
+
+// keyfileCheck returns the value stored in vault_meta to recognise the right keyfile
+func keyfileCheck(digest []byte) string {
+	check := sha256.Sum256(append([]byte(keyfileCheckInfo), digest...))
+	return hex.EncodeToString(check[:])
+}
+
+// combineKeyfile mixes the keyfile digest into the password-derived key
+func combineKeyfile(passwordKey, digest []byte) []byte {
+	mac := hmac.New(sha256.New, digest)
+	mac.Write(passwordKey)
+	return mac.Sum(nil)
+}
+
+// RequiresKeyfile reports whether unlocking the vault needs a keyfile
+func (v *FileVault) RequiresKeyfile() bool {
+	_, found, err := getMeta(v.db, metaKeyfileCheck)
+	return err == nil && found
+}
+
+// loadKeyfile returns the digest of the configured keyfile if the vault
+// requires one, or nil if it does not
+func (v *FileVault) loadKeyfile() ([]byte, error) {
+	check, found, err := getMeta(v.db, metaKeyfileCheck)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	path := KeyfilePath()
+	if path == "" {
+		return nil, ErrKeyfileRequired
+	}
+	digest, err := readKeyfileDigest(path)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(keyfileCheck(digest)), []byte(check)) {
+		return nil, ErrInvalidKeyfile
+	}
+	return digest, nil
+}
+
+// ChangeKeyfile rewraps the data key so that unlocking needs the master
+// password and the keyfile at path, replacing any keyfile required before.
+// An empty path removes the keyfile requirement. The new path is saved to
+// config.yaml so later unlocks find it.
+func (v *FileVault) ChangeKeyfile(masterPassword, path string) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	if v.IsLocked() {
+		return result, ErrVaultLocked
+	}
+
+	var newDigest []byte
+	if path != "" {
+		var err error
+		if newDigest, err = readKeyfileDigest(path); err != nil {
+			return result, err
+		}
+	}
+
+	params, _, err := loadKDFParams(v.db)
+	if err != nil {
+		return result, err
+	}
+	oldDigest, err := v.loadKeyfile()
+	if err != nil {
+		return result, err
+	}
+	oldKey, err := derivePasswordKey(masterPassword, params, oldDigest)
+	if err != nil {
+		return result, err
+	}
+	dataKey, err := v.unwrapDataKey(oldKey)
+	secure.Wipe(oldKey)
+	if err != nil {
+		return result, err
+	}
+	defer secure.Wipe(dataKey)
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if _, err := wrapDataKey(tx, dataKey, masterPassword, newDigest); err != nil {
+		return result, err
+	}
+	if newDigest != nil {
+		err = setMeta(tx, metaKeyfileCheck, keyfileCheck(newDigest))
+	} else {
+		_, err = tx.Exec("DELETE FROM vault_meta WHERE key = ?", metaKeyfileCheck)
+	}
+	if err != nil {
+		return result, err
+	}
+	if err := tx.Commit(); err != nil {
+		LogError("Failed to change keyfile: %v", err)
+		return result, err
+	}
+
+	SetKeyfile(path)
+	Config.Keyfile = path
+	if err := SaveConfig(); err != nil {
+		LogError("Failed to save keyfile path to config: %v", err)
+		return result, err
+	}
+
+	if path != "" {
+		LogInfo("Vault now requires keyfile %s", path)
+	} else {
+		LogInfo("Keyfile requirement removed")
+	}
+	result.SyncError = v.Sync()
+	return result, nil
+}
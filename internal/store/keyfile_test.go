@@ -0,0 +1,92 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeKeyfile writes a keyfile with the given contents and returns its path
+func writeKeyfile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vault.key")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestChangeKeyfile(t *testing.T) {
+	v := newTestVault(t, "password")
+	t.Cleanup(func() { SetKeyfile("") })
+	first := writeKeyfile(t, "first keyfile")
+	second := writeKeyfile(t, "second keyfile")
+
+	if _, err := v.ChangeKeyfile("wrong", first); err != ErrInvalidPassword {
+		t.Fatalf("ChangeKeyfile with the wrong password = %v, want ErrInvalidPassword", err)
+	}
+	if v.RequiresKeyfile() {
+		t.Fatal("keyfile required after a failed change")
+	}
+
+	if _, err := v.ChangeKeyfile("password", first); err != nil {
+		t.Fatal(err)
+	}
+	if !v.RequiresKeyfile() {
+		t.Fatal("keyfile not required after setting one")
+	}
+	v.Lock()
+	SetKeyfile("")
+	Config.Keyfile = ""
+	if err := v.Unlock("password"); err != ErrKeyfileRequired {
+		t.Fatalf("Unlock without the keyfile = %v, want ErrKeyfileRequired", err)
+	}
+	SetKeyfile(second)
+	if err := v.Unlock("password"); err != ErrInvalidKeyfile {
+		t.Fatalf("Unlock with another keyfile = %v, want ErrInvalidKeyfile", err)
+	}
+
+	// The new path is saved, so a reopened vault finds it without --keyfile
+	SetKeyfile("")
+	v.Close()
+	v = openTestVault(t)
+	if Config.Keyfile != first {
+		t.Fatalf("config keyfile = %q, want %q", Config.Keyfile, first)
+	}
+	if err := v.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := v.GetByName("GitHub"); err != nil || entry.Password != "hunter2" {
+		t.Fatalf("GetByName with the new keyfile = %v, %v", entry, err)
+	}
+
+	// Replacing it retires the old one
+	if _, err := v.ChangeKeyfile("password", second); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+	SetKeyfile(first)
+	if err := v.Unlock("password"); err != ErrInvalidKeyfile {
+		t.Fatalf("Unlock with the replaced keyfile = %v, want ErrInvalidKeyfile", err)
+	}
+	SetKeyfile(second)
+	if err := v.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.ChangeKeyfile("password", filepath.Join(t.TempDir(), "missing.key")); !errors.Is(err, ErrKeyfileMissing) {
+		t.Fatalf("ChangeKeyfile to a missing file = %v, want ErrKeyfileMissing", err)
+	}
+
+	if _, err := v.ChangeKeyfile("password", ""); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+	if v.RequiresKeyfile() || KeyfilePath() != "" {
+		t.Fatalf("keyfile still required (%q) after clearing it", KeyfilePath())
+	}
+	if err := v.Unlock("password"); err != nil {
+		t.Fatalf("Unlock after clearing the keyfile: %v", err)
+	}
+}
@@ -0,0 +1,502 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"lockin/internal/secure"
+	"time"
+)
+
+// Create initializes a new vault protected by the master password and unlocks it.
+// Rows are encrypted with a random data key that is stored wrapped by the
+// password-derived key.
+func (v *FileVault) Create(masterPassword string) error {
+	if v.Exists() {
+		return ErrVaultExists
+	}
+
+	dataKey, err := newDataKey()
+	if err != nil {
+		return err
+	}
+
+	// A keyfile configured at creation time becomes a required second factor
+	var keyfileDigest []byte
+	if path := KeyfilePath(); path != "" {
+		if keyfileDigest, err = readKeyfileDigest(path); err != nil {
+			return err
+		}
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	params, err := wrapDataKey(tx, dataKey, masterPassword, keyfileDigest)
+	if err != nil {
+		return err
+	}
+	if keyfileDigest != nil {
+		if err := setMeta(tx, metaKeyfileCheck, keyfileCheck(keyfileDigest)); err != nil {
+			return err
+		}
+		LogInfo("Vault requires keyfile %s", KeyfilePath())
+	}
+	vaultID, err := loadVaultID(tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	LogInfo("Vault created with %s", params.Algorithm)
+	v.vaultID = vaultID
+	if err := v.setMasterKey(dataKey); err != nil {
+		return err
+	}
+	v.isUnlocked = true
+	return nil
+}
+
+// Unlock unlocks the vault with the master password. Vaults still on an
+// older key derivation have their data key rewrapped with Argon2id once the
+// password is known. After repeated failures it returns ErrUnlockThrottled
+// until UnlockDelay has passed.
+func (v *FileVault) Unlock(masterPassword string) error {
+	if !v.Exists() {
+		return ErrVaultNotFound
+	}
+
+	delay, err := v.unlockDelay()
+	if err != nil {
+		return err
+	}
+	if delay > 0 {
+		LogError("Unlock refused: %s remaining before next attempt", delay.Round(time.Second))
+		return ErrUnlockThrottled
+	}
+
+	params, found, err := loadKDFParams(v.db)
+	if err != nil {
+		LogError("Failed to load KDF parameters: %v", err)
+		return err
+	}
+	if !found {
+		// Vault predates vault_meta and still uses the shared legacy salt
+		params = legacyKDFParams()
+	}
+
+	if v.vaultID, err = loadVaultID(v.db); err != nil {
+		return err
+	}
+
+	keyfileDigest, err := v.loadKeyfile()
+	if err != nil {
+		LogError("Unlock failed: %v", err)
+		// A wrong keyfile is a guess like a wrong password; a missing one is not
+		if err == ErrInvalidKeyfile {
+			v.recordFailedUnlock()
+		}
+		return err
+	}
+
+	passwordKey, err := derivePasswordKey(masterPassword, params, keyfileDigest)
+	if err != nil {
+		return err
+	}
+	dataKey, err := v.unwrapDataKey(passwordKey)
+	secure.Wipe(passwordKey)
+	if err != nil {
+		if err == ErrInvalidPassword {
+			LogError("Unlock failed: invalid master password")
+			v.recordFailedUnlock()
+		}
+		return err
+	}
+
+	if params.Algorithm != kdfArgon2id {
+		LogInfo("Upgrading key derivation from %s to %s", params.Algorithm, kdfArgon2id)
+		if err := v.rewrapDataKey(dataKey, masterPassword, keyfileDigest); err != nil {
+			LogError("Failed to upgrade key derivation: %v", err)
+		} else if err := v.Sync(); err != nil {
+			LogError("Sync after key derivation upgrade failed: %v", err)
+		}
+	}
+
+	return v.afterUnlock(dataKey)
+}
+
+// afterUnlock finishes opening the vault with its data key, however the key
+// was recovered: it clears the unlock backoff, reconnects the SMB share that
+// Lock closed, re-encrypts data older versions stored in plaintext or
+// unbound, removes backups from schema upgrades and purges expired trash
+func (v *FileVault) afterUnlock(dataKey []byte) error {
+	v.resetFailedUnlocks()
+	v.reconnectSMB()
+
+	if err := v.setMasterKey(dataKey); err != nil {
+		return err
+	}
+	v.isUnlocked = true
+
+	if err := v.encryptPlaintextColumns(); err != nil {
+		LogError("Failed to encrypt plaintext columns: %v", err)
+		v.Lock()
+		return err
+	}
+	if err := v.rebindConcealedFields(); err != nil {
+		LogError("Failed to bind concealed fields: %v", err)
+		v.Lock()
+		return err
+	}
+
+	// The upgraded vault opened, so the pre-upgrade copy is no longer needed
+	removeMigrationBackups(GetDBPath())
+
+	v.purgeExpiredTrash()
+	return nil
+}
+
+// ChangeMasterPassword rewraps the data key under a key derived from
+// newPassword. Rows are not touched since they are encrypted with the data key.
+func (v *FileVault) ChangeMasterPassword(oldPassword, newPassword string) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	if v.IsLocked() {
+		return result, ErrVaultLocked
+	}
+
+	params, _, err := loadKDFParams(v.db)
+	if err != nil {
+		return result, err
+	}
+	keyfileDigest, err := v.loadKeyfile()
+	if err != nil {
+		return result, err
+	}
+	oldKey, err := derivePasswordKey(oldPassword, params, keyfileDigest)
+	if err != nil {
+		return result, err
+	}
+	dataKey, err := v.unwrapDataKey(oldKey)
+	secure.Wipe(oldKey)
+	if err != nil {
+		return result, err
+	}
+	defer secure.Wipe(dataKey)
+
+	// The keyfile requirement carries over to the new password
+	if err := v.rewrapDataKey(dataKey, newPassword, keyfileDigest); err != nil {
+		LogError("Failed to change master password: %v", err)
+		return result, err
+	}
+
+	LogInfo("Master password changed")
+	result.SyncError = v.Sync()
+	return result, nil
+}
+
+// unwrapDataKey recovers the data key with the password-derived key. Vaults
+// from before envelope encryption are migrated to a fresh data key here.
+func (v *FileVault) unwrapDataKey(passwordKey []byte) ([]byte, error) {
+	wrapped, found, err := getMeta(v.db, metaWrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return unwrapKey(passwordKey, wrapped)
+	}
+
+	if err := v.verifyKey(passwordKey); err != nil {
+		return nil, err
+	}
+	return v.migrateToDataKey(passwordKey)
+}
+
+// verifyKey checks a password-derived key on a vault without a wrapped data
+// key, using the stored verifier or, failing that, the first row.
+func (v *FileVault) verifyKey(key []byte) error {
+	verifier, found, err := getMeta(v.db, metaVerifier)
+	if err != nil {
+		return err
+	}
+	if found {
+		return checkVerifier(key, verifier)
+	}
+
+	var encUsername string
+	err = v.db.QueryRow("SELECT username FROM credentials ORDER BY id LIMIT 1").Scan(&encUsername)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := decryptWithKey(key, encUsername, nil); err != nil {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// migrateToDataKey re-encrypts every row from the password-derived key to a
+// new random data key and stores it wrapped by the password-derived key
+func (v *FileVault) migrateToDataKey(passwordKey []byte) ([]byte, error) {
+	LogInfo("Migrating vault to envelope encryption")
+
+	dataKey, err := newDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapKey(passwordKey, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := reencryptCredentials(tx, v.vaultID, passwordKey, dataKey); err != nil {
+		LogError("Envelope encryption migration failed: %v", err)
+		return nil, err
+	}
+	if err := setMeta(tx, metaWrappedKey, wrapped); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM vault_meta WHERE key = ?", metaVerifier); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	LogInfo("Vault migrated to envelope encryption")
+	if err := v.Sync(); err != nil {
+		LogError("Sync after migration failed: %v", err)
+	}
+	return dataKey, nil
+}
+
+// rewrapDataKey replaces the stored wrapped data key in one transaction
+func (v *FileVault) rewrapDataKey(dataKey []byte, masterPassword string, keyfileDigest []byte) error {
+	tx, err := v.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := wrapDataKey(tx, dataKey, masterPassword, keyfileDigest); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// derivePasswordKey derives the key-encryption key from the master password,
+// mixing in the keyfile digest when the vault requires one
+func derivePasswordKey(masterPassword string, params kdfParams, keyfileDigest []byte) ([]byte, error) {
+	key, err := deriveKey(masterPassword, params)
+	if err != nil || keyfileDigest == nil {
+		return key, err
+	}
+	return combineKeyfile(key, keyfileDigest), nil
+}
+
+// wrapDataKey derives a key from the master password (and keyfile, if any)
+// with fresh parameters and stores the data key wrapped by it, returning the
+// parameters used
+func wrapDataKey(q querier, dataKey []byte, masterPassword string, keyfileDigest []byte) (kdfParams, error) {
+	params, err := newKDFParams()
+	if err != nil {
+		return kdfParams{}, err
+	}
+	passwordKey, err := derivePasswordKey(masterPassword, params, keyfileDigest)
+	if err != nil {
+		return kdfParams{}, err
+	}
+	wrapped, err := wrapKey(passwordKey, dataKey)
+	secure.Wipe(passwordKey)
+	if err != nil {
+		return kdfParams{}, err
+	}
+
+	if err := saveKDFParams(q, params); err != nil {
+		return kdfParams{}, err
+	}
+	if err := setMeta(q, metaWrappedKey, wrapped); err != nil {
+		return kdfParams{}, err
+	}
+	return params, nil
+}
+
+// reencryptCredentials re-encrypts every row's secrets from oldKey to newKey.
+// A row that fails to decrypt means oldKey is wrong and yields ErrInvalidPassword.
+func reencryptCredentials(tx *sql.Tx, vaultID string, oldKey, newKey []byte) error {
+	type secretRow struct {
+		id       int64
+		username string
+		password string
+	}
+
+	rows, err := tx.Query("SELECT id, username, password FROM credentials")
+	if err != nil {
+		return err
+	}
+	var secrets []secretRow
+	for rows.Next() {
+		var r secretRow
+		if err := rows.Scan(&r.id, &r.username, &r.password); err != nil {
+			rows.Close()
+			return err
+		}
+		secrets = append(secrets, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range secrets {
+		usernameAD := fieldAD(vaultID, tableCredentials, r.id, "username")
+		passwordAD := fieldAD(vaultID, tableCredentials, r.id, "password")
+
+		username, err := decryptWithKey(oldKey, r.username, usernameAD)
+		if err != nil {
+			return ErrInvalidPassword
+		}
+		password, err := decryptWithKey(oldKey, r.password, passwordAD)
+		if err != nil {
+			return ErrInvalidPassword
+		}
+
+		encUsername, err := encryptWithKey(newKey, username, usernameAD)
+		if err != nil {
+			return err
+		}
+		encPassword, err := encryptWithKey(newKey, password, passwordAD)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE credentials SET username=?, password=? WHERE id=?", encUsername, encPassword, r.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptPlaintextColumns encrypts the name, url and notes of rows written
+// before those columns were encrypted, recognised by a missing name_index.
+// Older versions allowed names differing only by case, which share a name
+// index; all but the oldest such entry are renamed.
+func (v *FileVault) encryptPlaintextColumns() error {
+	type plainRow struct {
+		id      int64
+		name    string
+		url     sql.NullString
+		notes   sql.NullString
+		deleted bool
+	}
+
+	rows, err := v.db.Query("SELECT id, name, url, notes, deleted_at IS NOT NULL FROM credentials WHERE name_index IS NULL ORDER BY id")
+	if err != nil {
+		return err
+	}
+	var plain []plainRow
+	for rows.Next() {
+		var r plainRow
+		if err := rows.Scan(&r.id, &r.name, &r.url, &r.notes, &r.deleted); err != nil {
+			rows.Close()
+			return err
+		}
+		plain = append(plain, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+
+	LogInfo("Encrypting names, URLs and notes of %d entries", len(plain))
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Name indexes already in use by entries outside the trash
+	taken := make(map[string]bool)
+	indexRows, err := tx.Query("SELECT name_index FROM credentials WHERE name_index IS NOT NULL AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	for indexRows.Next() {
+		var index string
+		if err := indexRows.Scan(&index); err != nil {
+			indexRows.Close()
+			return err
+		}
+		taken[index] = true
+	}
+	indexRows.Close()
+	if err := indexRows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range plain {
+		index, err := v.nameIndex(r.name)
+		if err != nil {
+			return err
+		}
+		// Only entries outside the trash must have unique names
+		if !r.deleted {
+			original := r.name
+			n := 1
+			for taken[index] {
+				n++
+				r.name = fmt.Sprintf("%s (%d)", original, n)
+				if index, err = v.nameIndex(r.name); err != nil {
+					return err
+				}
+			}
+			// The name itself is not logged, as it is now stored encrypted
+			if n > 1 {
+				LogWarn("Entry %d shared its name with another entry in a different case; renamed with suffix (%d)", r.id, n)
+			}
+			taken[index] = true
+		}
+		encName, err := v.encryptField(tableCredentials, r.id, "name", r.name)
+		if err != nil {
+			return err
+		}
+		encURL, err := v.encryptField(tableCredentials, r.id, "url", r.url.String)
+		if err != nil {
+			return err
+		}
+		encNotes, err := v.encryptField(tableCredentials, r.id, "notes", r.notes.String)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE credentials SET name=?, name_index=?, url=?, notes=? WHERE id=?",
+			encName, index, encURL, encNotes, r.id)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := v.Sync(); err != nil {
+		LogError("Sync after encrypting columns failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrapKey(t *testing.T) {
+	kek, _ := newDataKey()
+	dataKey, _ := newDataKey()
+
+	wrapped, err := wrapKey(kek, dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwrapped, err := unwrapKey(kek, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unwrapped, dataKey) {
+		t.Error("unwrapped key differs from the wrapped one")
+	}
+
+	otherKEK, _ := newDataKey()
+	if _, err := unwrapKey(otherKEK, wrapped); err != ErrInvalidPassword {
+		t.Errorf("unwrap with the wrong key = %v, want ErrInvalidPassword", err)
+	}
+
+	// A data key sealed for recovery must not unwrap as the password-wrapped one
+	recoveryWrapped, _ := sealWithKey(kek, dataKey, []byte(metaRecoveryKey))
+	if _, err := unwrapKey(kek, recoveryWrapped); err != ErrInvalidPassword {
+		t.Errorf("unwrap of the recovery key = %v, want ErrInvalidPassword", err)
+	}
+
+	short, _ := wrapKey(kek, dataKey[:16])
+	if _, err := unwrapKey(kek, short); err != ErrInvalidPassword {
+		t.Errorf("unwrap of a short key = %v, want ErrInvalidPassword", err)
+	}
+}
+
+func TestChangeMasterPassword(t *testing.T) {
+	v := newTestVault(t, "old password")
+
+	var before string
+	if err := v.db.QueryRow("SELECT password FROM credentials").Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.ChangeMasterPassword("wrong password", "new password"); err != ErrInvalidPassword {
+		t.Fatalf("ChangeMasterPassword with the wrong password = %v, want ErrInvalidPassword", err)
+	}
+	if _, err := v.ChangeMasterPassword("old password", "new password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the wrapped key changes; rows stay encrypted with the same data key
+	var after string
+	if err := v.db.QueryRow("SELECT password FROM credentials").Scan(&after); err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Error("changing the master password re-encrypted the rows")
+	}
+
+	v.Lock()
+	if err := v.Unlock("old password"); err != ErrInvalidPassword {
+		t.Errorf("Unlock with the old password = %v, want ErrInvalidPassword", err)
+	}
+	if err := v.Unlock("new password"); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := v.GetByName("GitHub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Password != "hunter2" {
+		t.Errorf("password = %q after changing the master password, want hunter2", entry.Password)
+	}
+}
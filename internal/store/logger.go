@@ -54,6 +54,14 @@ func LogInfo(format string, args ...any) {
 	}
 }
 
+// LogWarn logs a warning message
+func LogWarn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if logger != nil {
+		logger.Printf("[%s] WARN: %s", timestamp(), msg)
+	}
+}
+
 // LogError logs an error message
 func LogError(format string, args ...any) {
 	msg := fmt.Sprintf(format, args...)
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strconv"
+)
+
+// Keys stored in the vault_meta table
+const (
+	metaKDF           = "kdf"
+	metaKDFSalt       = "kdf_salt"
+	metaKDFIterations = "kdf_iterations"
+	metaKDFMemory     = "kdf_memory"
+	metaKDFTime       = "kdf_time"
+	metaKDFThreads    = "kdf_threads"
+	metaVerifier      = "verifier"
+	metaWrappedKey    = "wrapped_key"
+	metaVaultID       = "vault_id"
+	metaKeyfileCheck  = "keyfile_check"
+	metaRecoveryKey   = "recovery_wrapped_key"
+
+	metaFailedUnlocks    = "failed_unlocks"
+	metaLastFailedUnlock = "last_failed_unlock"
+
+	metaRebindConcealed = "rebind_concealed_fields"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// getMeta reads a value from vault_meta, reporting whether it was present
+func getMeta(q querier, key string) (string, bool, error) {
+	var value string
+	err := q.QueryRow("SELECT value FROM vault_meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// setMeta writes a value to vault_meta, replacing any existing one
+func setMeta(q querier, key, value string) error {
+	_, err := q.Exec(`
+		INSERT INTO vault_meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	return err
+}
+
+// loadKDFParams reads the key derivation parameters, reporting whether they were present
+func loadKDFParams(q querier) (kdfParams, bool, error) {
+	algorithm, found, err := getMeta(q, metaKDF)
+	if err != nil || !found {
+		return kdfParams{}, false, err
+	}
+
+	params := kdfParams{Algorithm: algorithm}
+
+	salt, _, err := getMeta(q, metaKDFSalt)
+	if err != nil {
+		return kdfParams{}, false, err
+	}
+	if params.Salt, err = base64.StdEncoding.DecodeString(salt); err != nil {
+		return kdfParams{}, false, err
+	}
+
+	switch algorithm {
+	case kdfPBKDF2:
+		iterations, err := getMetaUint(q, metaKDFIterations, 32)
+		if err != nil {
+			return kdfParams{}, false, err
+		}
+		params.Iterations = int(iterations)
+
+	case kdfArgon2id:
+		memory, err := getMetaUint(q, metaKDFMemory, 32)
+		if err != nil {
+			return kdfParams{}, false, err
+		}
+		passes, err := getMetaUint(q, metaKDFTime, 32)
+		if err != nil {
+			return kdfParams{}, false, err
+		}
+		threads, err := getMetaUint(q, metaKDFThreads, 8)
+		if err != nil {
+			return kdfParams{}, false, err
+		}
+		params.Memory = uint32(memory)
+		params.Time = uint32(passes)
+		params.Threads = uint8(threads)
+	}
+
+	if err := params.validate(); err != nil {
+		return kdfParams{}, false, err
+	}
+	return params, true, nil
+}
+
+// getMetaUint reads an unsigned integer value from vault_meta
+func getMetaUint(q querier, key string, bitSize int) (uint64, error) {
+	value, _, err := getMeta(q, key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(value, 10, bitSize)
+}
+
+// saveKDFParams writes the key derivation parameters, replacing any previous ones
+func saveKDFParams(q querier, params kdfParams) error {
+	_, err := q.Exec("DELETE FROM vault_meta WHERE key IN (?, ?, ?, ?, ?, ?)",
+		metaKDF, metaKDFSalt, metaKDFIterations, metaKDFMemory, metaKDFTime, metaKDFThreads)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{
+		metaKDF:     params.Algorithm,
+		metaKDFSalt: base64.StdEncoding.EncodeToString(params.Salt),
+	}
+	switch params.Algorithm {
+	case kdfPBKDF2:
+		values[metaKDFIterations] = strconv.Itoa(params.Iterations)
+	case kdfArgon2id:
+		values[metaKDFMemory] = strconv.FormatUint(uint64(params.Memory), 10)
+		values[metaKDFTime] = strconv.FormatUint(uint64(params.Time), 10)
+		values[metaKDFThreads] = strconv.FormatUint(uint64(params.Threads), 10)
+	}
+
+	for key, value := range values {
+		if err := setMeta(q, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadVaultID reads the vault identifier, generating one for vaults that lack it
+func loadVaultID(q querier) (string, error) {
+	id, found, err := getMeta(q, metaVaultID)
+	if err != nil || found {
+		return id, err
+	}
+
+	if id, err = newVaultID(); err != nil {
+		return "", err
+	}
+	if err := setMeta(q, metaVaultID, id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
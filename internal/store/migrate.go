@@ -0,0 +1,302 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// migration is one step in the evolution of the database schema. Step i
+// brings the schema to version i+1, recorded in PRAGMA user_version.
+type migration struct {
+	description string
+	apply       func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in order. Released steps must never
+// be edited or reordered; append new ones to the end. The first steps
+// describe the schema that existed before versioning and must tolerate
+// databases that already have it.
+var migrations = []migration{
+	{"create credentials and vault_meta tables", migrateBaseTables},
+	{"add name blind index", migrateNameIndex},
+	{"add password history", migratePasswordHistory},
+	{"add trash", migrateTrash},
+	{"add custom fields", migrateCustomFields},
+	{"add tags", migrateTags},
+	{"add one-time password keys", migrateOTP},
+	{"add entry types", migrateEntryTypes},
+	{"add attachments", migrateAttachments},
+	{"add breach checks", migrateBreaches},
+	{"bind concealed flag to custom field values", migrateConcealedFields},
+}
+
+// schemaVersion is the schema version this binary writes
+var schemaVersion = len(migrations)
+
+// migrate brings the database at path up to schemaVersion, backing it up
+// first. A database from a newer binary is refused with ErrSchemaTooNew.
+func migrate(db *sql.DB, path string) error {
+	var current int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return err
+	}
+	if current > schemaVersion {
+		LogError("Database schema version %d is newer than supported version %d", current, schemaVersion)
+		return ErrSchemaTooNew
+	}
+	if current == schemaVersion {
+		return nil
+	}
+
+	hasTables, err := hasTables(db)
+	if err != nil {
+		return err
+	}
+	if hasTables {
+		// Only the latest backup is kept; older ones are of no further use
+		removeMigrationBackups(path)
+		backup := fmt.Sprintf("%s.v%d.bak", path, current)
+		if err := backupDatabase(db, backup); err != nil {
+			LogError("Failed to back up database before migrating: %v", err)
+			return err
+		}
+		LogWarn("Database backed up to %s; it is removed after the next successful unlock", backup)
+	}
+
+	for version := current + 1; version <= schemaVersion; version++ {
+		m := migrations[version-1]
+		if err := applyMigration(db, version, m); err != nil {
+			LogError("Schema migration %d (%s) failed: %v", version, m.description, err)
+			return err
+		}
+		LogInfo("Applied schema migration %d: %s", version, m.description)
+	}
+	return nil
+}
+
+// applyMigration runs one step and records its version in the same transaction
+func applyMigration(db *sql.DB, version int, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.apply(tx); err != nil {
+		return err
+	}
+	// PRAGMA does not accept bound parameters
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// hasTables reports whether the database holds any tables yet
+func hasTables(db *sql.DB) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&count)
+	return count > 0, err
+}
+
+// backupDatabase writes a consistent copy of the database to path
+func backupDatabase(db *sql.DB, path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// removeMigrationBackups deletes the backups migrate made of the database at
+// path. A backup of an older schema can hold data that has since been
+// encrypted or rewrapped, so it must not outlive the upgrade.
+func removeMigrationBackups(path string) {
+	backups, err := filepath.Glob(path + ".v*.bak")
+	if err != nil {
+		return
+	}
+	for _, backup := range backups {
+		if err := os.Remove(backup); err != nil {
+			LogError("Failed to remove database backup %s: %v", backup, err)
+			continue
+		}
+		LogInfo("Removed database backup %s", backup)
+	}
+}
+
+// columnExists reports whether table already has the named column
+func columnExists(q querier, table, column string) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	return count > 0, err
+}
+
+func migrateBaseTables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS credentials (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			username TEXT NOT NULL,
+			password TEXT NOT NULL,
+			url TEXT,
+			notes TEXT,
+			created_at INTEGER,
+			updated_at INTEGER
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Metadata table for per-vault settings such as KDF parameters
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS vault_meta (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)
+	`)
+	return err
+}
+
+// migrateNameIndex adds the keyed blind index of the encrypted name, used
+// for lookups and uniqueness
+func migrateNameIndex(tx *sql.Tx) error {
+	exists, err := columnExists(tx, "credentials", "name_index")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := tx.Exec("ALTER TABLE credentials ADD COLUMN name_index TEXT"); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_credentials_name_index ON credentials(name_index)")
+	return err
+}
+
+func migratePasswordHistory(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE password_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			credential_id INTEGER NOT NULL REFERENCES credentials(id),
+			password TEXT NOT NULL,
+			changed_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("CREATE INDEX idx_password_history_credential ON password_history(credential_id)")
+	return err
+}
+
+// migrateTrash adds the soft-delete column. Names only need to be unique
+// among entries outside the trash.
+func migrateTrash(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE credentials ADD COLUMN deleted_at INTEGER"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DROP INDEX IF EXISTS idx_credentials_name_index"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("CREATE UNIQUE INDEX idx_credentials_name_index ON credentials(name_index) WHERE deleted_at IS NULL")
+	return err
+}
+
+func migrateCustomFields(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE custom_fields (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			credential_id INTEGER NOT NULL REFERENCES credentials(id),
+			position INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			concealed INTEGER NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("CREATE INDEX idx_custom_fields_credential ON custom_fields(credential_id)")
+	return err
+}
+
+func migrateTags(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			name_index TEXT NOT NULL UNIQUE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		CREATE TABLE credential_tags (
+			credential_id INTEGER NOT NULL REFERENCES credentials(id),
+			tag_id INTEGER NOT NULL REFERENCES tags(id),
+			PRIMARY KEY (credential_id, tag_id)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("CREATE INDEX idx_credential_tags_tag ON credential_tags(tag_id)")
+	return err
+}
+
+func migrateOTP(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE credentials ADD COLUMN otp TEXT")
+	return err
+}
+
+func migrateEntryTypes(tx *sql.Tx) error {
+	_, err := tx.Exec("ALTER TABLE credentials ADD COLUMN type TEXT")
+	return err
+}
+
+func migrateAttachments(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			credential_id INTEGER NOT NULL REFERENCES credentials(id),
+			name TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			data BLOB NOT NULL,
+			created_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("CREATE INDEX idx_attachments_credential ON attachments(credential_id)")
+	return err
+}
+
+func migrateBreaches(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE breaches (
+			credential_id INTEGER PRIMARY KEY REFERENCES credentials(id),
+			count TEXT NOT NULL,
+			checked_at INTEGER NOT NULL
+		)
+	`)
+	return err
+}
+
+// migrateConcealedFields marks concealed values encrypted before the flag was
+// bound to them. They can only be re-encrypted with the data key, so that is
+// left to the next unlock.
+func migrateConcealedFields(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		INSERT INTO vault_meta (key, value)
+		SELECT ?, '1' WHERE EXISTS (SELECT 1 FROM custom_fields WHERE concealed = 1)
+	`, metaRebindConcealed)
+	return err
+}
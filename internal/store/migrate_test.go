@@ -0,0 +1,218 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testdata/baseline.db was written by the first release, before schema
+// versioning: names, URLs and notes in plaintext, usernames and passwords
+// encrypted without associated data under a PBKDF2 key with the shared salt
+const baselinePassword = "correct horse battery staple"
+
+var baselineEntries = []Entry{
+	{Name: "Bank", Username: "12345678", Password: "🔒 unicode ✓", Notes: "PIN is not stored here"},
+	{Name: "Email", Username: "alice@example.com", Password: "pa55word!"},
+	{Name: "GitHub", Username: "alice", Password: "hunter2", URL: "https://github.com", Notes: "work account"},
+}
+
+// openBaselineVault copies a vault from testdata into a fresh home directory
+// and opens it, which runs every migration
+func openBaselineVault(t *testing.T, fixture string) *FileVault {
+	t.Helper()
+	dir := testConfigDir(t)
+
+	data, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DBFileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return openTestVault(t)
+}
+
+// checkEntries checks that the vault holds want, in order, with every secret intact
+func checkEntries(t *testing.T, v *FileVault, want []Entry) {
+	t.Helper()
+	entries, err := v.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("List returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		got, err := v.GetByName(w.Name)
+		if err != nil {
+			t.Fatalf("GetByName(%q): %v", w.Name, err)
+		}
+		if entries[i].Name != w.Name || got.Username != w.Username ||
+			got.Password != w.Password || got.URL != w.URL || got.Notes != w.Notes {
+			t.Errorf("entry %d = %q %+v, want %+v", i, entries[i].Name, *got, w)
+		}
+
+		password, err := v.Password(got.ID)
+		if err != nil {
+			t.Fatalf("Password(%q): %v", w.Name, err)
+		}
+		if string(password.Bytes()) != w.Password {
+			t.Errorf("Password(%q) = %q, want %q", w.Name, password.Bytes(), w.Password)
+		}
+		password.Destroy()
+	}
+}
+
+func TestMigrateBaselineVault(t *testing.T) {
+	v := openBaselineVault(t, "baseline.db")
+	backup := GetDBPath() + ".v0.bak"
+
+	var version int
+	if err := v.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != schemaVersion {
+		t.Fatalf("user_version = %d, want %d", version, schemaVersion)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Fatalf("no backup before the first unlock: %v", err)
+	}
+	if !v.Exists() {
+		t.Fatal("migrated vault does not exist")
+	}
+
+	// A wrong password must not migrate anything or remove the backup
+	if err := v.Unlock("wrong password"); err != ErrInvalidPassword {
+		t.Fatalf("Unlock with wrong password = %v, want ErrInvalidPassword", err)
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Fatalf("backup removed after a failed unlock: %v", err)
+	}
+
+	if err := v.Unlock(baselinePassword); err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, v, baselineEntries)
+
+	params, found, err := loadKDFParams(v.db)
+	if err != nil || !found || params.Algorithm != kdfArgon2id {
+		t.Errorf("KDF after unlock = %+v, %v, %v, want %s", params, found, err, kdfArgon2id)
+	}
+	if _, found, _ := getMeta(v.db, metaWrappedKey); !found {
+		t.Error("no wrapped data key after unlock")
+	}
+	var plaintext int
+	err = v.db.QueryRow("SELECT COUNT(*) FROM credentials WHERE name_index IS NULL OR name IN ('Bank', 'Email', 'GitHub')").Scan(&plaintext)
+	if err != nil || plaintext != 0 {
+		t.Errorf("%d rows still have plaintext names (%v)", plaintext, err)
+	}
+	if matches, _ := filepath.Glob(GetDBPath() + ".v*.bak"); len(matches) != 0 {
+		t.Errorf("backups left after unlock: %v", matches)
+	}
+
+	// The upgraded vault opens again and takes writes
+	v.Lock()
+	if err := v.Unlock(baselinePassword); err != nil {
+		t.Fatalf("second unlock: %v", err)
+	}
+
+	github, err := v.GetByName("github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	github.Password = "correct-horse"
+	if _, err := v.Update(*github); err != nil {
+		t.Fatal(err)
+	}
+	added := Entry{Name: "Wiki", Username: "bob", Password: "s3cret", URL: "https://wiki.example.com"}
+	if _, err := v.Add(added); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]Entry(nil), baselineEntries...)
+	want[2].Password = "correct-horse"
+	want = append(want, added)
+	checkEntries(t, v, want)
+
+	history, err := v.History(github.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("history has %d changes, want 1", len(history))
+	}
+	old, err := v.HistoryPassword(history[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Destroy()
+	if string(old.Bytes()) != "hunter2" {
+		t.Errorf("history password = %q, want the baseline password", old.Bytes())
+	}
+}
+
+// testdata/baseline_case_duplicates.db holds names that differ only by case,
+// which the first release allowed through renaming
+func TestMigrateCaseDuplicateNames(t *testing.T) {
+	v := openBaselineVault(t, "baseline_case_duplicates.db")
+	if err := v.Unlock(baselinePassword); err != nil {
+		t.Fatal(err)
+	}
+
+	// The oldest entry keeps its name
+	want := []Entry{
+		{Name: "GitHub", Username: "alice", Password: "hunter2"},
+		{Name: "github (2)", Username: "alice@work.example", Password: "w0rk"},
+		{Name: "GITHUB (3)", Username: "root", Password: "toor"},
+	}
+	checkEntries(t, v, want)
+
+	v.Lock()
+	if err := v.Unlock(baselinePassword); err != nil {
+		t.Fatalf("second unlock: %v", err)
+	}
+	checkEntries(t, v, want)
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DBFileName)
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(db, path); err != ErrSchemaTooNew {
+		t.Errorf("migrate = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestMigrateCurrentSchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DBFileName)
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A new database needs no backup, and one already current is left alone
+	for i := 0; i < 2; i++ {
+		if err := migrate(db, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if matches, _ := filepath.Glob(path + ".v*.bak"); len(matches) != 0 {
+		t.Errorf("backups of a new database: %v", matches)
+	}
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != schemaVersion {
+		t.Errorf("user_version = %d, %v, want %d", version, err, schemaVersion)
+	}
+}
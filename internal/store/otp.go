@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+	"lockin/internal/otp"
+)
+
+// OTPKey decrypts and parses an entry's one-time password key. It returns
+// ErrEntryNotFound if the entry has none.
+func (v *FileVault) OTPKey(id int64) (*otp.Key, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	var encOTP sql.NullString
+	err := v.db.QueryRow("SELECT otp FROM credentials WHERE id = ? AND deleted_at IS NULL", id).Scan(&encOTP)
+	if err == sql.ErrNoRows || (err == nil && encOTP.String == "") {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := v.decryptSecret(tableCredentials, id, "otp", encOTP.String)
+	if err != nil {
+		return nil, err
+	}
+	defer uri.Destroy()
+
+	return otp.Parse(string(uri.Bytes()))
+}
+
+// NextHOTP advances an entry's HOTP counter, so the next code is shown, and
+// returns the updated key
+func (v *FileVault) NextHOTP(id int64) (*otp.Key, SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	key, err := v.OTPKey(id)
+	if err != nil {
+		return nil, result, err
+	}
+	if key.Type != otp.TypeHOTP {
+		return key, result, nil
+	}
+
+	key.Counter++
+	encOTP, err := v.encryptField(tableCredentials, id, "otp", key.URI())
+	if err != nil {
+		return nil, result, err
+	}
+	if _, err := v.db.Exec("UPDATE credentials SET otp = ? WHERE id = ?", encOTP, id); err != nil {
+		return nil, result, err
+	}
+
+	result.SyncError = v.Sync()
+	return key, result, nil
+}
+
+// normalizeOTP validates an entry's one-time password key, given as an
+// otpauth:// URI or a bare base32 secret, and stores it in URI form
+func normalizeOTP(entry *Entry) error {
+	if entry.OTP == "" {
+		return nil
+	}
+	key, err := otp.Parse(entry.OTP)
+	if err != nil {
+		return err
+	}
+	entry.OTP = key.URI()
+	return nil
+}
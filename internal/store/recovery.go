@@ -0,0 +1,158 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"io"
+	"lockin/internal/secure"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// recoveryKeySize is the number of random bytes in a recovery key (160 bits)
+const recoveryKeySize = 20
+
+// recoveryGroupSize is how many base32 characters are shown per dash-separated group
+const recoveryGroupSize = 4
+
+// HKDF info string for the key that wraps the data key for recovery
+const recoveryKeyInfo = "lockin recovery key"
+
+// recoveryEncoding is unpadded base32, which avoids ambiguous characters
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryKey creates a new recovery key that can later reset the
+// master password, replacing any previous one. The returned key is not
+// stored and must be shown to the user once.
+func (v *FileVault) GenerateRecoveryKey() (string, error) {
+	dataKey := v.getMasterKey()
+	if dataKey == nil {
+		return "", ErrVaultLocked
+	}
+
+	raw := make([]byte, recoveryKeySize)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	defer secure.Wipe(raw)
+
+	kek, err := recoveryKEK(raw, v.vaultID)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := sealWithKey(kek, dataKey, []byte(metaRecoveryKey))
+	secure.Wipe(kek)
+	if err != nil {
+		return "", err
+	}
+	if err := setMeta(v.db, metaRecoveryKey, wrapped); err != nil {
+		return "", err
+	}
+
+	LogInfo("Recovery key generated")
+	if err := v.Sync(); err != nil {
+		LogError("Sync after generating recovery key failed: %v", err)
+	}
+	return formatRecoveryKey(raw), nil
+}
+
+// HasRecoveryKey reports whether a recovery key has been set up for the vault
+func (v *FileVault) HasRecoveryKey() bool {
+	_, found, err := getMeta(v.db, metaRecoveryKey)
+	return err == nil && found
+}
+
+// Recover unlocks the vault with a recovery key and sets a new master password
+func (v *FileVault) Recover(recoveryKey, newPassword string) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	wrapped, found, err := getMeta(v.db, metaRecoveryKey)
+	if err != nil {
+		return result, err
+	}
+	if !found {
+		return result, ErrNoRecoveryKey
+	}
+
+	raw, err := parseRecoveryKey(recoveryKey)
+	if err != nil {
+		return result, ErrInvalidRecoveryKey
+	}
+	defer secure.Wipe(raw)
+
+	vaultID, err := loadVaultID(v.db)
+	if err != nil {
+		return result, err
+	}
+	kek, err := recoveryKEK(raw, vaultID)
+	if err != nil {
+		return result, err
+	}
+	dataKey, err := openWithKey(kek, wrapped, []byte(metaRecoveryKey))
+	secure.Wipe(kek)
+	if err != nil || len(dataKey) != dataKeySize {
+		LogError("Recovery failed: invalid recovery key")
+		return result, ErrInvalidRecoveryKey
+	}
+
+	// A required keyfile stays required under the new password
+	keyfileDigest, err := v.loadKeyfile()
+	if err != nil {
+		secure.Wipe(dataKey)
+		return result, err
+	}
+	if err := v.rewrapDataKey(dataKey, newPassword, keyfileDigest); err != nil {
+		secure.Wipe(dataKey)
+		return result, err
+	}
+
+	v.vaultID = vaultID
+	if err := v.afterUnlock(dataKey); err != nil {
+		return result, err
+	}
+
+	LogInfo("Master password reset with recovery key")
+	result.SyncError = v.Sync()
+	return result, nil
+}
+
+// recoveryKEK derives the key that wraps the data key from a raw recovery key.
+// The recovery key is high-entropy, so HKDF is used instead of a slow KDF.
+func recoveryKEK(raw []byte, vaultID string) ([]byte, error) {
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, raw, []byte(vaultID), []byte(recoveryKeyInfo)), kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
+
+// formatRecoveryKey renders a raw recovery key as dash-separated base32 groups
+func formatRecoveryKey(raw []byte) string {
+	encoded := recoveryEncoding.EncodeToString(raw)
+	var groups []string
+	for i := 0; i < len(encoded); i += recoveryGroupSize {
+		groups = append(groups, encoded[i:min(i+recoveryGroupSize, len(encoded))])
+	}
+	return strings.Join(groups, "-")
+}
+
+// parseRecoveryKey accepts a recovery key with any casing, dashes or spaces
+func parseRecoveryKey(key string) ([]byte, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(key)))
+
+	raw, err := recoveryEncoding.DecodeString(cleaned)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != recoveryKeySize {
+		return nil, ErrInvalidRecoveryKey
+	}
+	return raw, nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// zeroRecoveryKey is well formed but opens no vault
+const zeroRecoveryKey = "AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA-AAAA"
+
+func TestRecoveryKeyFormat(t *testing.T) {
+	raw := []byte("0123456789abcdefghij")
+	key := formatRecoveryKey(raw)
+	if want := "GAYT-EMZU-GU3D-OOBZ-MFRG-GZDF-MZTW-Q2LK"; key != want {
+		t.Fatalf("formatRecoveryKey = %s, want %s", key, want)
+	}
+
+	for _, input := range []string{
+		key,
+		strings.ToLower(key),
+		strings.ReplaceAll(key, "-", " "),
+		strings.ReplaceAll(key, "-", ""),
+		"  " + key + "\n",
+	} {
+		parsed, err := parseRecoveryKey(input)
+		if err != nil || !bytes.Equal(parsed, raw) {
+			t.Errorf("parseRecoveryKey(%q) = %q, %v, want %q", input, parsed, err, raw)
+		}
+	}
+
+	for _, input := range []string{
+		"",
+		key[:len(key)-5],
+		key + "-AAAA",
+		// 0, 1 and 8 are not in the base32 alphabet
+		strings.Replace(key, "G", "0", 1),
+	} {
+		if _, err := parseRecoveryKey(input); err == nil {
+			t.Errorf("parseRecoveryKey(%q) succeeded", input)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	v := newTestVault(t, "forgotten")
+
+	v.Lock()
+	if _, err := v.Recover(zeroRecoveryKey, "new password"); err != ErrNoRecoveryKey {
+		t.Fatalf("Recover without a recovery key = %v, want ErrNoRecoveryKey", err)
+	}
+	if err := v.Unlock("forgotten"); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := v.GenerateRecoveryKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.HasRecoveryKey() {
+		t.Error("HasRecoveryKey is false after generating one")
+	}
+	v.Lock()
+
+	// Recovering clears the backoff from the attempts that came before it
+	for i := 0; i < freeUnlockAttempts; i++ {
+		v.Unlock("guess")
+	}
+	if v.UnlockDelay() == 0 {
+		t.Fatal("no unlock delay after repeated failures")
+	}
+
+	if _, err := v.Recover(zeroRecoveryKey, "new password"); err != ErrInvalidRecoveryKey {
+		t.Fatalf("Recover with the wrong key = %v, want ErrInvalidRecoveryKey", err)
+	}
+	if _, err := v.Recover(strings.ToLower(key), "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if v.IsLocked() {
+		t.Fatal("vault is locked after recovering")
+	}
+	if delay := v.UnlockDelay(); delay != 0 {
+		t.Errorf("unlock delay after recovering = %s, want 0", delay)
+	}
+	entry, err := v.GetByName("GitHub")
+	if err != nil || entry.Password != "hunter2" {
+		t.Fatalf("GetByName after recovering = %v, %v", entry, err)
+	}
+
+	v.Lock()
+	if err := v.Unlock("forgotten"); err != ErrInvalidPassword {
+		t.Errorf("Unlock with the old password = %v, want ErrInvalidPassword", err)
+	}
+	if err := v.Unlock("new password"); err != nil {
+		t.Fatal(err)
+	}
+	// The recovery key keeps working after being used
+	v.Lock()
+	if _, err := v.Recover(key, "newer password"); err != nil {
+		t.Errorf("second recovery: %v", err)
+	}
+}
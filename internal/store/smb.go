@@ -16,9 +16,12 @@ type smbConnection struct {
 	share   *smb2.Share
 }
 
+// dialSMB connects to the configured share; tests replace it
+var dialSMB = connectSMB
+
 // connectSMB establishes a connection to the SMB share using config
 func connectSMB() (*smbConnection, error) {
-	cfg := SMBConfig
+	cfg := Config
 	if !cfg.Enabled {
 		LogDebug("SMB is disabled in config")
 		return nil, nil
@@ -196,7 +199,7 @@ func (v *FileVault) initSMB(newDB bool) {
 		return
 	}
 
-	smb, err := connectSMB()
+	smb, err := dialSMB()
 	if err != nil {
 		LogError("SMB connection failed: %v", err)
 		return
@@ -208,6 +211,23 @@ func (v *FileVault) initSMB(newDB bool) {
 	smb.syncFromSMBCheck(newDB)
 }
 
+// reconnectSMB reopens the SMB connection that Lock closed, so writes after
+// the vault is unlocked again are synced. The remote copy is not pulled, as
+// the local database is already open.
+func (v *FileVault) reconnectSMB() {
+	if !IsSMBEnabled() || v.smb != nil {
+		return
+	}
+
+	smb, err := dialSMB()
+	if err != nil {
+		LogError("SMB reconnection failed: %v", err)
+		return
+	}
+	v.smb = smb
+	LogInfo("SMB connection reopened after unlock")
+}
+
 // closeSMB closes the SMB connection
 func (v *FileVault) closeSMB() {
 	if v.smb != nil {
@@ -0,0 +1,38 @@
+package store
+
+import "testing"
+
+func TestSyncAfterRelock(t *testing.T) {
+	v := newTestVault(t, "password")
+
+	// Stand in for the share; a connection without one skips the copy
+	dials := 0
+	dialSMB = func() (*smbConnection, error) {
+		dials++
+		return &smbConnection{}, nil
+	}
+	Config.Enabled = true
+	t.Cleanup(func() {
+		dialSMB = connectSMB
+		Config.Enabled = false
+	})
+
+	v.Lock()
+	if v.IsSyncEnabled() {
+		t.Fatal("sync still enabled while locked")
+	}
+	if err := v.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if dials != 1 || !v.IsSyncEnabled() {
+		t.Fatalf("after unlock: %d dials, sync enabled %v", dials, v.IsSyncEnabled())
+	}
+
+	result, err := v.Add(Entry{Name: "Email", Username: "bob", Password: "pa55word"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.SyncEnabled || result.SyncError != nil {
+		t.Errorf("Add after relocking = %+v, want a successful sync", result)
+	}
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Errors for SSH key files protected by a passphrase when none, or the wrong
+// one, was given
+var (
+	ErrSSHPassphraseRequired = errors.New("SSH key is protected by a passphrase")
+	ErrSSHPassphraseWrong    = errors.New("incorrect passphrase for SSH key")
+)
+
+// Names of the custom fields describing an SSH key entry's public half
+const (
+	sshPublicKeyField   = "Public Key"
+	sshFingerprintField = "Fingerprint"
+)
+
+// LoadSSHKey reads the SSH private key at path into entry, decrypting it with
+// passphrase if it is protected. The key is kept unencrypted in the entry's
+// password, which the vault encrypts, and its public key and fingerprint are
+// set as custom fields. An empty name defaults to the file name.
+func LoadSSHKey(entry *Entry, path, passphrase string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	key, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			return ErrSSHPassphraseRequired
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return ErrSSHPassphraseWrong
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Keep the comment of the matching .pub file, if there is one
+	comment := filepath.Base(path)
+	if pub, err := os.ReadFile(path + ".pub"); err == nil {
+		if _, c, _, _, err := ssh.ParseAuthorizedKey(pub); err == nil && c != "" {
+			comment = c
+		}
+	}
+
+	block, err := ssh.MarshalPrivateKey(key, comment)
+	if err != nil {
+		return err
+	}
+
+	entry.Type = TypeSSHKey
+	if entry.Name == "" {
+		entry.Name = filepath.Base(path)
+	}
+	entry.Password = string(pem.EncodeToMemory(block))
+
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " " + comment
+	setField(entry, sshPublicKeyField, publicKey)
+	setField(entry, sshFingerprintField, ssh.FingerprintSHA256(signer.PublicKey()))
+	return nil
+}
+
+// SSHSigner parses the private key of an SSH key entry
+func (v *FileVault) SSHSigner(id int64) (ssh.Signer, error) {
+	key, err := v.Password(id)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Destroy()
+
+	return ssh.ParsePrivateKey(key.Bytes())
+}
+
+// setField sets the value of the custom field with the given name, adding it
+// if the entry does not have one
+func setField(entry *Entry, name, value string) {
+	for i := range entry.Fields {
+		if entry.Fields[i].Name == name {
+			entry.Fields[i].Value = value
+			return
+		}
+	}
+	entry.Fields = append(entry.Fields, CustomField{Name: name, Value: value})
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Table name used in ciphertext associated data
+const tableTags = "tags"
+
+// tagPrefix marks a search term that filters by tag, as in "tag:work"
+const tagPrefix = "tag:"
+
+// Tags returns the names of all tags in use by entries outside the trash, sorted
+func (v *FileVault) Tags() ([]string, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	rows, err := v.db.Query(`
+		SELECT DISTINCT t.id, t.name FROM tags t
+		JOIN credential_tags ct ON ct.tag_id = t.id
+		JOIN credentials c ON c.id = ct.credential_id
+		WHERE c.deleted_at IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var id int64
+		var encName string
+		if err := rows.Scan(&id, &encName); err != nil {
+			return nil, err
+		}
+		name, err := v.decryptField(tableTags, id, "name", encName)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortTags(tags)
+	return tags, nil
+}
+
+// loadTags returns the tags of every entry, keyed by entry id
+func (v *FileVault) loadTags() (map[int64][]string, error) {
+	rows, err := v.db.Query(`
+		SELECT ct.credential_id, t.id, t.name FROM credential_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[int64]string)
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var entryID, tagID int64
+		var encName string
+		if err := rows.Scan(&entryID, &tagID, &encName); err != nil {
+			return nil, err
+		}
+		// Decrypt each tag once, however many entries carry it
+		name, ok := names[tagID]
+		if !ok {
+			if name, err = v.decryptField(tableTags, tagID, "name", encName); err != nil {
+				return nil, err
+			}
+			names[tagID] = name
+		}
+		tags[entryID] = append(tags[entryID], name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tags {
+		sortTags(t)
+	}
+	return tags, nil
+}
+
+// attachTags fills in the Tags of each entry
+func (v *FileVault) attachTags(entries []Entry) error {
+	tags, err := v.loadTags()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Tags = tags[entries[i].ID]
+	}
+	return nil
+}
+
+// entryTags returns the tags of one entry
+func (v *FileVault) entryTags(id int64) ([]string, error) {
+	rows, err := v.db.Query(`
+		SELECT t.id, t.name FROM credential_tags ct
+		JOIN tags t ON t.id = ct.tag_id
+		WHERE ct.credential_id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tagID int64
+		var encName string
+		if err := rows.Scan(&tagID, &encName); err != nil {
+			return nil, err
+		}
+		name, err := v.decryptField(tableTags, tagID, "name", encName)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortTags(tags)
+	return tags, nil
+}
+
+// saveTags replaces an entry's tags, creating tags that do not exist yet and
+// dropping ones no longer used by any entry
+func (v *FileVault) saveTags(tx *sql.Tx, id int64, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM credential_tags WHERE credential_id = ?", id); err != nil {
+		return err
+	}
+
+	for _, tag := range NormalizeTags(tags) {
+		tagID, err := v.tagID(tx, tag)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO credential_tags (credential_id, tag_id) VALUES (?, ?)", id, tagID); err != nil {
+			return err
+		}
+	}
+
+	return deleteUnusedTags(tx)
+}
+
+// tagID returns the id of the tag with the given name, creating it if needed
+func (v *FileVault) tagID(tx *sql.Tx, tag string) (int64, error) {
+	index, err := v.tagIndex(tag)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = tx.QueryRow("SELECT id FROM tags WHERE name_index = ?", index).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// Ciphertexts are bound to their row id, so insert first and encrypt after
+	res, err := tx.Exec("INSERT INTO tags (name, name_index) VALUES ('', ?)", index)
+	if err != nil {
+		return 0, err
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+	encName, err := v.encryptField(tableTags, id, "name", tag)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE tags SET name = ? WHERE id = ?", encName, id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// deleteUnusedTags removes tags that no entry carries any more
+func deleteUnusedTags(tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM credential_tags)")
+	return err
+}
+
+// NormalizeTags trims tags and drops empty and case-insensitively repeated ones
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ParseQuery splits a search query into its free text and the tags named by
+// "tag:" terms
+func ParseQuery(query string) (text string, tags []string) {
+	var words []string
+	for _, term := range strings.Fields(query) {
+		if len(term) > len(tagPrefix) && strings.EqualFold(term[:len(tagPrefix)], tagPrefix) {
+			tags = append(tags, term[len(tagPrefix):])
+		} else {
+			words = append(words, term)
+		}
+	}
+	return strings.Join(words, " "), tags
+}
+
+// HasTags reports whether entryTags includes every one of tags, ignoring case
+func HasTags(entryTags, tags []string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range entryTags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTags sorts tag names case-insensitively
+func sortTags(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"strconv"
+	"time"
+)
+
+// Failed unlocks beyond freeUnlockAttempts must wait unlockBackoffBase,
+// doubling with each further failure up to maxUnlockBackoff
+const (
+	freeUnlockAttempts = 3
+	unlockBackoffBase  = time.Second
+	maxUnlockBackoff   = 5 * time.Minute
+)
+
+// UnlockDelay returns how long to wait before the next unlock attempt is
+// allowed, or 0 if one can be made now
+func (v *FileVault) UnlockDelay() time.Duration {
+	delay, err := v.unlockDelay()
+	if err != nil {
+		LogError("Failed to read unlock attempts: %v", err)
+	}
+	return delay
+}
+
+func (v *FileVault) unlockDelay() (time.Duration, error) {
+	attempts, err := getMetaCounter(v.db, metaFailedUnlocks, 32)
+	if err != nil || attempts < freeUnlockAttempts {
+		return 0, err
+	}
+	last, err := getMetaCounter(v.db, metaLastFailedUnlock, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	backoff := unlockBackoff(int(attempts))
+	remaining := time.Until(time.Unix(0, int64(last)).Add(backoff))
+	if remaining <= 0 {
+		return 0, nil
+	}
+	// Don't let a clock set backwards extend the wait indefinitely
+	return min(remaining, backoff), nil
+}
+
+// unlockBackoff returns the wait imposed after the given number of failures
+func unlockBackoff(attempts int) time.Duration {
+	if attempts < freeUnlockAttempts {
+		return 0
+	}
+	backoff := unlockBackoffBase
+	for i := freeUnlockAttempts; i < attempts && backoff < maxUnlockBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxUnlockBackoff)
+}
+
+// recordFailedUnlock counts a failed unlock attempt and stamps its time
+func (v *FileVault) recordFailedUnlock() {
+	attempts, err := getMetaCounter(v.db, metaFailedUnlocks, 32)
+	if err != nil {
+		attempts = 0
+	}
+	attempts++
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		LogError("Failed to record unlock attempt: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := setMeta(tx, metaFailedUnlocks, strconv.FormatUint(attempts, 10)); err != nil {
+		LogError("Failed to record unlock attempt: %v", err)
+		return
+	}
+	if err := setMeta(tx, metaLastFailedUnlock, strconv.FormatInt(time.Now().UnixNano(), 10)); err != nil {
+		LogError("Failed to record unlock attempt: %v", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		LogError("Failed to record unlock attempt: %v", err)
+		return
+	}
+
+	if delay := unlockBackoff(int(attempts)); delay > 0 {
+		LogError("Failed unlock attempt %d, next attempt allowed in %s", attempts, delay)
+	} else {
+		LogError("Failed unlock attempt %d", attempts)
+	}
+}
+
+// resetFailedUnlocks clears the failed attempt counter after a successful unlock
+func (v *FileVault) resetFailedUnlocks() {
+	_, err := v.db.Exec("DELETE FROM vault_meta WHERE key IN (?, ?)", metaFailedUnlocks, metaLastFailedUnlock)
+	if err != nil {
+		LogError("Failed to reset unlock attempts: %v", err)
+	}
+}
+
+// getMetaCounter reads an unsigned integer value from vault_meta, treating a
+// missing key as 0
+func getMetaCounter(q querier, key string, bitSize int) (uint64, error) {
+	_, found, err := getMeta(q, key)
+	if err != nil || !found {
+		return 0, err
+	}
+	return getMetaUint(q, key, bitSize)
+}
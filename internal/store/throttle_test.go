@@ -0,0 +1,134 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnlockBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{0, 0},
+		{freeUnlockAttempts - 1, 0},
+		{freeUnlockAttempts, time.Second},
+		{freeUnlockAttempts + 1, 2 * time.Second},
+		{freeUnlockAttempts + 2, 4 * time.Second},
+		{freeUnlockAttempts + 8, 256 * time.Second},
+		{freeUnlockAttempts + 9, maxUnlockBackoff},
+		{1000, maxUnlockBackoff},
+	}
+	for _, tt := range tests {
+		if got := unlockBackoff(tt.attempts); got != tt.backoff {
+			t.Errorf("unlockBackoff(%d) = %s, want %s", tt.attempts, got, tt.backoff)
+		}
+	}
+}
+
+// failedUnlocks returns the stored count of failed unlock attempts
+func failedUnlocks(t *testing.T, v *FileVault) uint64 {
+	t.Helper()
+	attempts, err := getMetaCounter(v.db, metaFailedUnlocks, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return attempts
+}
+
+// expireBackoff moves the last failed attempt far enough back that the next
+// attempt is allowed
+func expireBackoff(t *testing.T, v *FileVault) {
+	t.Helper()
+	if err := setMeta(v.db, metaLastFailedUnlock, "0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnlockThrottle(t *testing.T) {
+	v := newTestVault(t, "password")
+	v.Lock()
+
+	for i := 0; i < freeUnlockAttempts; i++ {
+		if delay := v.UnlockDelay(); delay != 0 {
+			t.Fatalf("delay after %d failures = %s, want 0", i, delay)
+		}
+		if err := v.Unlock("guess"); err != ErrInvalidPassword {
+			t.Fatalf("Unlock with a wrong password = %v, want ErrInvalidPassword", err)
+		}
+	}
+	if delay := v.UnlockDelay(); delay <= 0 || delay > time.Second {
+		t.Fatalf("delay after %d failures = %s, want up to 1s", freeUnlockAttempts, delay)
+	}
+
+	// Throttled attempts are refused without being counted, even with the right password
+	if err := v.Unlock("password"); err != ErrUnlockThrottled {
+		t.Fatalf("Unlock while throttled = %v, want ErrUnlockThrottled", err)
+	}
+	if n := failedUnlocks(t, v); n != freeUnlockAttempts {
+		t.Fatalf("%d failures recorded, want %d", n, freeUnlockAttempts)
+	}
+
+	// Each further failure doubles the wait
+	expireBackoff(t, v)
+	v.Unlock("guess")
+	if delay := v.UnlockDelay(); delay <= time.Second || delay > 2*time.Second {
+		t.Fatalf("delay after %d failures = %s, want up to 2s", freeUnlockAttempts+1, delay)
+	}
+
+	// The count is kept in the vault, so reopening it does not clear the wait
+	v.Close()
+	v = openTestVault(t)
+	if delay := v.UnlockDelay(); delay <= time.Second {
+		t.Fatalf("delay after reopening = %s, want over 1s", delay)
+	}
+	if n := failedUnlocks(t, v); n != freeUnlockAttempts+1 {
+		t.Fatalf("%d failures recorded after reopening, want %d", n, freeUnlockAttempts+1)
+	}
+
+	expireBackoff(t, v)
+	if err := v.Unlock("password"); err != nil {
+		t.Fatal(err)
+	}
+	if n := failedUnlocks(t, v); n != 0 {
+		t.Errorf("%d failures recorded after a successful unlock, want 0", n)
+	}
+	v.Lock()
+	if delay := v.UnlockDelay(); delay != 0 {
+		t.Errorf("delay after a successful unlock = %s, want 0", delay)
+	}
+}
+
+func TestUnlockThrottleKeyfile(t *testing.T) {
+	v := newTestVault(t, "password")
+	t.Cleanup(func() { SetKeyfile("") })
+	if _, err := v.ChangeKeyfile("password", writeKeyfile(t, "right keyfile")); err != nil {
+		t.Fatal(err)
+	}
+	v.Lock()
+
+	// A wrong keyfile counts as a failed attempt
+	SetKeyfile(writeKeyfile(t, "wrong keyfile"))
+	for i := 0; i < freeUnlockAttempts; i++ {
+		if err := v.Unlock("password"); err != ErrInvalidKeyfile {
+			t.Fatalf("Unlock with a wrong keyfile = %v, want ErrInvalidKeyfile", err)
+		}
+	}
+	if n := failedUnlocks(t, v); n != freeUnlockAttempts {
+		t.Fatalf("%d failures recorded, want %d", n, freeUnlockAttempts)
+	}
+	if err := v.Unlock("password"); err != ErrUnlockThrottled {
+		t.Fatalf("Unlock after wrong keyfiles = %v, want ErrUnlockThrottled", err)
+	}
+
+	// A missing keyfile is not a guess
+	expireBackoff(t, v)
+	SetKeyfile("")
+	Config.Keyfile = ""
+	if err := v.Unlock("password"); err != ErrKeyfileRequired {
+		t.Fatalf("Unlock without a keyfile = %v, want ErrKeyfileRequired", err)
+	}
+	if n := failedUnlocks(t, v); n != freeUnlockAttempts {
+		t.Errorf("%d failures recorded after a missing keyfile, want %d", n, freeUnlockAttempts)
+	}
+}
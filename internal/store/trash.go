@@ -0,0 +1,144 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Trash returns the entries in the trash, most recently deleted first
+func (v *FileVault) Trash() ([]Entry, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	rows, err := v.db.Query(`
+		SELECT id, type, name, username, password, otp, url, notes, created_at, updated_at, deleted_at
+		FROM credentials WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries, err := v.scanEntries(rows, false)
+	if err != nil {
+		return nil, err
+	}
+	return entries, v.attachTags(entries)
+}
+
+// Restore moves an entry out of the trash. It fails with ErrDuplicateEntry if
+// another entry has taken its name in the meantime.
+func (v *FileVault) Restore(id int64) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	if v.IsLocked() {
+		return result, ErrVaultLocked
+	}
+
+	var index sql.NullString
+	err := v.db.QueryRow("SELECT name_index FROM credentials WHERE id = ? AND deleted_at IS NOT NULL", id).Scan(&index)
+	if err == sql.ErrNoRows {
+		return result, ErrEntryNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+	if err := v.checkDuplicateName(index.String, id); err != nil {
+		return result, err
+	}
+
+	if _, err := v.db.Exec("UPDATE credentials SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		return result, err
+	}
+
+	result.SyncError = v.Sync()
+	return result, nil
+}
+
+// Purge permanently deletes an entry in the trash along with its history,
+// custom fields, tags, attachments and breach check
+func (v *FileVault) Purge(id int64) (SyncResult, error) {
+	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
+
+	if v.IsLocked() {
+		return result, ErrVaultLocked
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	purged, err := purgeTrash(tx, "id = ?", id)
+	if err != nil {
+		return result, err
+	}
+	if purged == 0 {
+		return result, ErrEntryNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	result.SyncError = v.Sync()
+	return result, nil
+}
+
+// purgeExpiredTrash permanently deletes entries that have been in the trash
+// longer than the configured retention period
+func (v *FileVault) purgeExpiredTrash() {
+	if Config.TrashRetentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -Config.TrashRetentionDays).Unix()
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		LogError("Failed to purge trash: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	purged, err := purgeTrash(tx, "deleted_at < ?", cutoff)
+	if err != nil {
+		LogError("Failed to purge trash: %v", err)
+		return
+	}
+	if purged == 0 {
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		LogError("Failed to purge trash: %v", err)
+		return
+	}
+
+	LogInfo("Purged %d entries from the trash older than %d days", purged, Config.TrashRetentionDays)
+	if err := v.Sync(); err != nil {
+		LogError("Sync after purging trash failed: %v", err)
+	}
+}
+
+// purgeTrash deletes the trashed entries matching cond, with their password
+// history, custom fields, tags, attachments and breach checks, returning how
+// many entries were deleted
+func purgeTrash(tx *sql.Tx, cond string, args ...any) (int64, error) {
+	where := "deleted_at IS NOT NULL AND " + cond
+
+	for _, table := range []string{"password_history", "custom_fields", "credential_tags", "attachments", "breaches"} {
+		_, err := tx.Exec("DELETE FROM "+table+" WHERE credential_id IN (SELECT id FROM credentials WHERE "+where+")", args...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	res, err := tx.Exec("DELETE FROM credentials WHERE "+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := deleteUnusedTags(tx); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,110 @@
+package store
+
+import "errors"
+
+// ErrInvalidEntryType is returned when an entry has an unknown type
+var ErrInvalidEntryType = errors.New("unknown entry type")
+
+// EntryType is the kind of item an entry holds. Type-specific data lives in
+// the entry's custom fields, seeded from the type's template.
+type EntryType string
+
+const (
+	TypeLogin    EntryType = "login"
+	TypeNote     EntryType = "note"
+	TypeCard     EntryType = "card"
+	TypeAPIKey   EntryType = "api_key"
+	TypeIdentity EntryType = "identity"
+	TypeDatabase EntryType = "database"
+	TypeSSHKey   EntryType = "ssh_key"
+)
+
+// EntryTypes lists every entry type in display order
+var EntryTypes = []EntryType{TypeLogin, TypeNote, TypeCard, TypeAPIKey, TypeIdentity, TypeDatabase, TypeSSHKey}
+
+// entryTypeInfo describes which standard entry fields a type uses and the
+// custom fields a new entry of that type starts with
+type entryTypeInfo struct {
+	label    string
+	login    bool // username and password
+	url      bool
+	template []CustomField
+}
+
+var entryTypeInfos = map[EntryType]entryTypeInfo{
+	TypeLogin: {label: "Login", login: true, url: true},
+	TypeNote:  {label: "Secure Note"},
+	TypeCard: {label: "Payment Card", template: []CustomField{
+		{Name: "Cardholder"},
+		{Name: "Number", Concealed: true},
+		{Name: "Expiry"},
+		{Name: "Security Code", Concealed: true},
+		{Name: "PIN", Concealed: true},
+	}},
+	TypeAPIKey: {label: "API Key", url: true, template: []CustomField{
+		{Name: "Key ID"},
+		{Name: "Secret", Concealed: true},
+	}},
+	TypeIdentity: {label: "Identity", template: []CustomField{
+		{Name: "Full Name"},
+		{Name: "Email"},
+		{Name: "Phone"},
+		{Name: "Address"},
+		{Name: "Date of Birth"},
+		{Name: "ID Number", Concealed: true},
+	}},
+	TypeDatabase: {label: "Database", login: true, template: []CustomField{
+		{Name: "Engine"},
+		{Name: "Host"},
+		{Name: "Port"},
+		{Name: "Database"},
+	}},
+	// The private key is kept in the password; LoadSSHKey fills in the rest
+	TypeSSHKey: {label: "SSH Key"},
+}
+
+// Valid reports whether t is a known entry type
+func (t EntryType) Valid() bool {
+	_, ok := entryTypeInfos[t]
+	return ok
+}
+
+// Label returns the type's display name
+func (t EntryType) Label() string {
+	if info, ok := entryTypeInfos[t]; ok {
+		return info.label
+	}
+	return string(t)
+}
+
+// HasLogin reports whether entries of this type have a username and password
+func (t EntryType) HasLogin() bool {
+	return entryTypeInfos[t].login
+}
+
+// HasURL reports whether entries of this type have a URL
+func (t EntryType) HasURL() bool {
+	return entryTypeInfos[t].url
+}
+
+// HasOTP reports whether entries of this type can hold a one-time password key
+func (t EntryType) HasOTP() bool {
+	return t == TypeLogin
+}
+
+// Template returns the custom fields a new entry of this type starts with
+func (t EntryType) Template() []CustomField {
+	return append([]CustomField(nil), entryTypeInfos[t].template...)
+}
+
+// normalizeType defaults an entry without a type to a login and rejects
+// unknown types
+func normalizeType(entry *Entry) error {
+	if entry.Type == "" {
+		entry.Type = TypeLogin
+	}
+	if !entry.Type.Valid() {
+		return ErrInvalidEntryType
+	}
+	return nil
+}
@@ -3,7 +3,9 @@ package store
 import (
 	"database/sql"
 	"errors"
+	"lockin/internal/secure"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,28 +19,52 @@ var (
 	ErrVaultLocked     = errors.New("vault is locked")
 	ErrEntryNotFound   = errors.New("entry not found")
 	ErrDuplicateEntry  = errors.New("entry with this name already exists")
+	ErrVaultExists     = errors.New("vault already exists")
+	ErrKeyfileRequired = errors.New("this vault requires a keyfile (set keyfile in config.yaml or pass --keyfile)")
+	ErrKeyfileMissing  = errors.New("keyfile not found")
+	ErrInvalidKeyfile  = errors.New("keyfile does not match this vault")
+	ErrUnlockThrottled = errors.New("too many failed unlock attempts")
+	ErrSchemaTooNew    = errors.New("vault was written by a newer version of lockin; please upgrade")
+	ErrCorruptVault    = errors.New("vault metadata is corrupt")
+
+	ErrInvalidRecoveryKey = errors.New("invalid recovery key")
+	ErrNoRecoveryKey      = errors.New("no recovery key has been set up for this vault")
 )
 
-// Entry represents a password entry in the vault
+// Entry represents a password entry in the vault. Password, OTP and Fields
+// are only loaded by Get and GetByName; List and Search leave them empty but
+// do include Tags.
 type Entry struct {
-	ID        int64  `json:"id"`
-	Name      string `json:"name"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
-	URL       string `json:"url,omitempty"`
-	Notes     string `json:"notes,omitempty"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	ID        int64     `json:"id"`
+	Type      EntryType `json:"type,omitempty"` // empty is treated as TypeLogin
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	OTP       string    `json:"otp,omitempty"` // otpauth:// URI
+	URL       string    `json:"url,omitempty"`
+	Notes     string    `json:"notes,omitempty"`
+	CreatedAt int64     `json:"created_at"`
+	UpdatedAt int64     `json:"updated_at"`
+	DeletedAt int64     `json:"deleted_at,omitempty"` // set while the entry is in the trash
+
+	Tags   []string      `json:"tags,omitempty"`
+	Fields []CustomField `json:"fields,omitempty"`
+
+	HasOTP bool `json:"-"` // set even when OTP itself is not loaded
 }
 
 // FileVault is a SQLite-based password vault with optional SMB sync
 type FileVault struct {
-	db            *sql.DB
-	smb           *smbConnection
-	obfuscatedKey []byte
-	isUnlocked    bool
+	db         *sql.DB
+	smb        *smbConnection
+	masterKey  *secure.Buffer
+	vaultID    string
+	isUnlocked bool
 }
 
+// Table name used in ciphertext associated data
+const tableCredentials = "credentials"
+
 // NewFileVault creates and initializes a new vault
 func NewFileVault() (*FileVault, error) {
 	// Ensure config directory exists
@@ -65,26 +91,16 @@ func NewFileVault() (*FileVault, error) {
 	}
 	LogInfo("Database opened: %s", GetDBPath())
 
-	// Create table if needed
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS credentials (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE,
-			username TEXT NOT NULL,
-			password TEXT NOT NULL,
-			url TEXT,
-			notes TEXT,
-			created_at INTEGER,
-			updated_at INTEGER
-		)
-	`)
-	if err != nil {
+	// Pull a newer copy from the share before looking at the schema, so a
+	// vault written by a newer version is refused rather than modified
+	v := &FileVault{db: db}
+	v.initSMB(!v.Exists())
+
+	if err := migrate(db, GetDBPath()); err != nil {
+		v.closeSMB()
 		db.Close()
 		return nil, err
 	}
-
-	v := &FileVault{db: db}
-	v.initSMB(!v.Exists())
 	return v, nil
 }
 
@@ -99,14 +115,6 @@ func (v *FileVault) Close() error {
 	return nil
 }
 
-// Unlock unlocks the vault with the master password
-func (v *FileVault) Unlock(masterPassword string) error {
-	key := deriveKey(masterPassword)
-	v.setMasterKey(key)
-	v.isUnlocked = true
-	return nil
-}
-
 // Lock locks the vault and clears sensitive data
 func (v *FileVault) Lock() {
 	v.clearMasterKey()
@@ -119,57 +127,97 @@ func (v *FileVault) IsLocked() bool {
 	return !v.isUnlocked
 }
 
-// Exists checks if any credentials exist
+// Exists checks if the vault has been created, either through Create or by
+// holding credentials from a version that predates vault_meta
 func (v *FileVault) Exists() bool {
+	if _, found, err := getMeta(v.db, metaKDF); err == nil && found {
+		return true
+	}
+
 	var count int
 	err := v.db.QueryRow("SELECT COUNT(*) FROM credentials").Scan(&count)
 	return err == nil && count > 0
 }
 
-// List returns all entries (decrypted)
+// List returns all entries (decrypted), sorted by name
 func (v *FileVault) List() ([]Entry, error) {
 	if v.IsLocked() {
 		return nil, ErrVaultLocked
 	}
 
 	rows, err := v.db.Query(`
-		SELECT id, name, username, password, url, notes, created_at, updated_at 
-		FROM credentials ORDER BY name ASC
+		SELECT id, type, name, username, password, otp, url, notes, created_at, updated_at, deleted_at
+		FROM credentials WHERE deleted_at IS NULL
 	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return v.scanEntries(rows)
+	entries, err := v.scanEntries(rows, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.attachTags(entries); err != nil {
+		return nil, err
+	}
+	sortEntries(entries)
+	return entries, nil
 }
 
-// Get retrieves an entry by ID
+// Get retrieves an entry by ID with its password, one-time password key and
+// concealed fields decrypted. These are ordinary strings that cannot be wiped
+// and stay in memory until collected, so Get is only for editing an entry;
+// Password and FieldValue read a single secret into locked memory.
 func (v *FileVault) Get(id int64) (*Entry, error) {
 	if v.IsLocked() {
 		return nil, ErrVaultLocked
 	}
 
 	row := v.db.QueryRow(`
-		SELECT id, name, username, password, url, notes, created_at, updated_at 
-		FROM credentials WHERE id = ?
+		SELECT id, type, name, username, password, otp, url, notes, created_at, updated_at, deleted_at
+		FROM credentials WHERE id = ? AND deleted_at IS NULL
 	`, id)
 
-	return v.scanEntry(row)
+	return v.scanFullEntry(row)
+}
+
+// Password decrypts an entry's password into locked memory. The caller must
+// Destroy the returned buffer once done with it.
+func (v *FileVault) Password(id int64) (*secure.Buffer, error) {
+	if v.IsLocked() {
+		return nil, ErrVaultLocked
+	}
+
+	var encPassword string
+	err := v.db.QueryRow("SELECT password FROM credentials WHERE id = ? AND deleted_at IS NULL", id).Scan(&encPassword)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return v.decryptSecret(tableCredentials, id, "password", encPassword)
 }
 
-// GetByName retrieves an entry by name (case-insensitive)
+// GetByName retrieves an entry by name (case-insensitive) through the blind index
 func (v *FileVault) GetByName(name string) (*Entry, error) {
 	if v.IsLocked() {
 		return nil, ErrVaultLocked
 	}
 
+	index, err := v.nameIndex(name)
+	if err != nil {
+		return nil, err
+	}
+
 	row := v.db.QueryRow(`
-		SELECT id, name, username, password, url, notes, created_at, updated_at 
-		FROM credentials WHERE LOWER(name) = LOWER(?)
-	`, name)
+		SELECT id, type, name, username, password, otp, url, notes, created_at, updated_at, deleted_at
+		FROM credentials WHERE name_index = ? AND deleted_at IS NULL
+	`, index)
 
-	return v.scanEntry(row)
+	return v.scanFullEntry(row)
 }
 
 // SyncResult contains the result of an operation with sync status
@@ -191,37 +239,77 @@ func (v *FileVault) Add(entry Entry) (SyncResult, error) {
 		return result, ErrVaultLocked
 	}
 
-	// Check for duplicate
-	var count int
-	if err := v.db.QueryRow("SELECT COUNT(*) FROM credentials WHERE LOWER(name) = LOWER(?)", entry.Name).Scan(&count); err != nil {
+	if err := normalizeType(&entry); err != nil {
 		return result, err
 	}
-	if count > 0 {
-		return result, ErrDuplicateEntry
+	if err := normalizeOTP(&entry); err != nil {
+		return result, err
 	}
 
-	encUsername, err := v.encrypt(entry.Username)
+	index, err := v.nameIndex(entry.Name)
 	if err != nil {
 		return result, err
 	}
-	encPassword, err := v.encrypt(entry.Password)
+
+	// Check for duplicate among entries not in the trash
+	if err := v.checkDuplicateName(index, 0); err != nil {
+		return result, err
+	}
+
+	// The list can be large, so it is searched before the write transaction starts
+	breachCount, breachFound, breachErr := lookupBreach(entry)
+
+	tx, err := v.db.Begin()
 	if err != nil {
 		return result, err
 	}
+	defer tx.Rollback()
 
+	// Ciphertexts are bound to their row id, so insert first and encrypt after
 	now := time.Now().Unix()
-	_, err = v.db.Exec(`
-		INSERT INTO credentials (name, username, password, url, notes, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, entry.Name, encUsername, encPassword, entry.URL, entry.Notes, now, now)
+	res, err := tx.Exec(`
+		INSERT INTO credentials (name, name_index, username, password, created_at, updated_at)
+		VALUES (?, ?, '', '', ?, ?)
+	`, index, index, now, now)
+	if err != nil {
+		return result, err
+	}
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		return result, err
+	}
+	if breachErr != nil {
+		LogError("Failed to check password of entry %d for breaches: %v", entry.ID, breachErr)
+	}
+
+	enc, err := v.encryptEntry(entry)
+	if err != nil {
+		return result, err
+	}
+	_, err = tx.Exec(`
+		UPDATE credentials SET type=?, name=?, username=?, password=?, otp=?, url=?, notes=? WHERE id=?
+	`, enc.Type, enc.Name, enc.Username, enc.Password, enc.OTP, enc.URL, enc.Notes, entry.ID)
+	if err != nil {
+		return result, err
+	}
+	if err := v.saveFields(tx, entry.ID, entry.Fields); err != nil {
+		return result, err
+	}
+	if err := v.saveTags(tx, entry.ID, entry.Tags); err != nil {
+		return result, err
+	}
+	if err := v.replaceBreach(tx, entry.ID, breachCount, breachFound); err != nil {
+		return result, err
+	}
 
+	err = tx.Commit()
 	if err == nil {
 		result.SyncError = v.Sync()
 	}
 	return result, err
 }
 
-// Update updates an existing entry
+// Update replaces an existing entry, including its password, tags and custom fields
 func (v *FileVault) Update(entry Entry) (SyncResult, error) {
 	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
 
@@ -230,34 +318,91 @@ func (v *FileVault) Update(entry Entry) (SyncResult, error) {
 	}
 
 	var count int
-	if err := v.db.QueryRow("SELECT COUNT(*) FROM credentials WHERE id = ?", entry.ID).Scan(&count); err != nil {
+	if err := v.db.QueryRow("SELECT COUNT(*) FROM credentials WHERE id = ? AND deleted_at IS NULL", entry.ID).Scan(&count); err != nil {
 		return result, err
 	}
 	if count == 0 {
 		return result, ErrEntryNotFound
 	}
 
-	encUsername, err := v.encrypt(entry.Username)
+	if err := normalizeType(&entry); err != nil {
+		return result, err
+	}
+	if err := normalizeOTP(&entry); err != nil {
+		return result, err
+	}
+
+	index, err := v.nameIndex(entry.Name)
 	if err != nil {
 		return result, err
 	}
-	encPassword, err := v.encrypt(entry.Password)
+
+	// Check for duplicate name on another entry
+	if err := v.checkDuplicateName(index, entry.ID); err != nil {
+		return result, err
+	}
+
+	enc, err := v.encryptEntry(entry)
 	if err != nil {
 		return result, err
 	}
 
+	// The list is searched before the write transaction starts, and only for
+	// a new password; the stored check of an unchanged one stays valid
+	var breachCount int
+	var breachFound bool
+	changed, err := v.passwordChanged(entry.ID, entry.Password)
+	if err != nil {
+		return result, err
+	}
+	if changed {
+		var breachErr error
+		breachCount, breachFound, breachErr = lookupBreach(entry)
+		if breachErr != nil {
+			LogError("Failed to check password of entry %d for breaches: %v", entry.ID, breachErr)
+		}
+	}
+
+	tx, err := v.db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	// Keep the replaced password so a half-finished rotation can be undone
 	now := time.Now().Unix()
-	_, err = v.db.Exec(`
-		UPDATE credentials SET name=?, username=?, password=?, url=?, notes=?, updated_at=? WHERE id=?
-	`, entry.Name, encUsername, encPassword, entry.URL, entry.Notes, now, entry.ID)
+	changed, err = v.recordPasswordChange(tx, entry.ID, entry.Password, now)
+	if err != nil {
+		return result, err
+	}
 
+	_, err = tx.Exec(`
+		UPDATE credentials SET type=?, name=?, name_index=?, username=?, password=?, otp=?, url=?, notes=?, updated_at=? WHERE id=?
+	`, enc.Type, enc.Name, index, enc.Username, enc.Password, enc.OTP, enc.URL, enc.Notes, now, entry.ID)
+	if err != nil {
+		return result, err
+	}
+	if err := v.saveFields(tx, entry.ID, entry.Fields); err != nil {
+		return result, err
+	}
+	if err := v.saveTags(tx, entry.ID, entry.Tags); err != nil {
+		return result, err
+	}
+	// The stored breach check belongs to the old password
+	if changed || !entry.Type.HasLogin() {
+		if err := v.replaceBreach(tx, entry.ID, breachCount, breachFound); err != nil {
+			return result, err
+		}
+	}
+
+	err = tx.Commit()
 	if err == nil {
 		result.SyncError = v.Sync()
 	}
 	return result, err
 }
 
-// Delete removes an entry by ID
+// Delete moves an entry to the trash, from where it can be restored or purged
 func (v *FileVault) Delete(id int64) (SyncResult, error) {
 	result := SyncResult{SyncEnabled: v.IsSyncEnabled()}
 
@@ -265,7 +410,7 @@ func (v *FileVault) Delete(id int64) (SyncResult, error) {
 		return result, ErrVaultLocked
 	}
 
-	dbResult, err := v.db.Exec("DELETE FROM credentials WHERE id = ?", id)
+	dbResult, err := v.db.Exec("UPDATE credentials SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now().Unix(), id)
 	if err != nil {
 		return result, err
 	}
@@ -282,102 +427,174 @@ func (v *FileVault) Delete(id int64) (SyncResult, error) {
 	return result, nil
 }
 
-// Search searches entries by name
+// Search searches entries by name, narrowed to entries carrying every tag
+// named by a "tag:" term. Names are encrypted at rest, so matching happens
+// after decryption.
 func (v *FileVault) Search(query string) ([]Entry, error) {
-	if v.IsLocked() {
-		return nil, ErrVaultLocked
-	}
-
-	rows, err := v.db.Query(`
-		SELECT id, name, username, password, url, notes, created_at, updated_at 
-		FROM credentials WHERE LOWER(name) LIKE LOWER(?) ORDER BY name ASC
-	`, "%"+strings.TrimSpace(query)+"%")
+	entries, err := v.List()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	return v.scanEntries(rows)
+	text, tags := ParseQuery(query)
+	text = strings.ToLower(text)
+	var results []Entry
+	for _, e := range entries {
+		if strings.Contains(strings.ToLower(e.Name), text) && HasTags(e.Tags, tags) {
+			results = append(results, e)
+		}
+	}
+	return results, nil
 }
 
-// scanEntry scans a single row into an Entry (with decryption)
-func (v *FileVault) scanEntry(row *sql.Row) (*Entry, error) {
-	var e Entry
-	var encUsername, encPassword string
-	var url, notes sql.NullString
-	var createdAt, updatedAt sql.NullInt64
-
-	err := row.Scan(&e.ID, &e.Name, &encUsername, &encPassword, &url, &notes, &createdAt, &updatedAt)
-	if err == sql.ErrNoRows {
-		return nil, ErrEntryNotFound
-	}
+// checkDuplicateName returns ErrDuplicateEntry if an entry outside the trash,
+// other than excludeID, already uses the name with the given blind index
+func (v *FileVault) checkDuplicateName(index string, excludeID int64) error {
+	var count int
+	err := v.db.QueryRow("SELECT COUNT(*) FROM credentials WHERE name_index = ? AND id != ? AND deleted_at IS NULL", index, excludeID).Scan(&count)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEntry
 	}
+	return nil
+}
 
-	e.Username, err = v.decrypt(encUsername)
-	if err != nil {
-		return nil, err
+// sortEntries sorts entries by name, case-insensitively
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+}
+
+// encryptEntry returns a copy of entry with every sensitive field encrypted
+// and bound to the entry's row
+func (v *FileVault) encryptEntry(entry Entry) (Entry, error) {
+	entryType := string(entry.Type)
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"type", &entryType},
+		{"name", &entry.Name},
+		{"username", &entry.Username},
+		{"password", &entry.Password},
+		{"otp", &entry.OTP},
+		{"url", &entry.URL},
+		{"notes", &entry.Notes},
+	}
+	for _, f := range fields {
+		// An empty otp column means the entry has no one-time password
+		if f.column == "otp" && *f.value == "" {
+			continue
+		}
+		enc, err := v.encryptField(tableCredentials, entry.ID, f.column, *f.value)
+		if err != nil {
+			return Entry{}, err
+		}
+		*f.value = enc
 	}
-	e.Password, err = v.decrypt(encPassword)
-	if err != nil {
+	entry.Type = EntryType(entryType)
+	return entry, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRow scans one credentials row into an Entry (with decryption). The
+// password and one-time password key are only decrypted when withPassword is set.
+func (v *FileVault) scanRow(row rowScanner, withPassword bool) (*Entry, error) {
+	var e Entry
+	var encName, encUsername, encPassword, entryType string
+	var encType, encOTP, url, notes sql.NullString
+	var createdAt, updatedAt, deletedAt sql.NullInt64
+
+	if err := row.Scan(&e.ID, &encType, &encName, &encUsername, &encPassword, &encOTP, &url, &notes, &createdAt, &updatedAt, &deletedAt); err != nil {
 		return nil, err
 	}
-
-	if url.Valid {
-		e.URL = url.String
+	e.HasOTP = encOTP.String != ""
+
+	fields := []struct {
+		column string
+		dst    *string
+		enc    string
+	}{
+		{"type", &entryType, encType.String},
+		{"name", &e.Name, encName},
+		{"username", &e.Username, encUsername},
+		{"password", &e.Password, encPassword},
+		{"otp", &e.OTP, encOTP.String},
+		{"url", &e.URL, url.String},
+		{"notes", &e.Notes, notes.String},
+	}
+	for _, f := range fields {
+		secret := f.column == "password" || f.column == "otp"
+		if f.enc == "" || (secret && !withPassword) {
+			continue
+		}
+		var err error
+		if *f.dst, err = v.decryptField(tableCredentials, e.ID, f.column, f.enc); err != nil {
+			return nil, err
+		}
 	}
-	if notes.Valid {
-		e.Notes = notes.String
+
+	// Rows from before entry types are logins
+	e.Type = EntryType(entryType)
+	if e.Type == "" {
+		e.Type = TypeLogin
 	}
+
 	if createdAt.Valid {
 		e.CreatedAt = createdAt.Int64
 	}
 	if updatedAt.Valid {
 		e.UpdatedAt = updatedAt.Int64
 	}
+	if deletedAt.Valid {
+		e.DeletedAt = deletedAt.Int64
+	}
 
 	return &e, nil
 }
 
+// scanEntry scans a single row into an Entry (with decryption)
+func (v *FileVault) scanEntry(row *sql.Row, withPassword bool) (*Entry, error) {
+	e, err := v.scanRow(row, withPassword)
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	return e, err
+}
+
+// scanFullEntry scans a single row into an Entry with its password, tags
+// and custom fields, concealed values included
+func (v *FileVault) scanFullEntry(row *sql.Row) (*Entry, error) {
+	e, err := v.scanEntry(row, true)
+	if err != nil {
+		return nil, err
+	}
+	if e.Tags, err = v.entryTags(e.ID); err != nil {
+		return nil, err
+	}
+	if e.Fields, err = v.loadFields(v.db, e.ID, true); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // scanEntries scans multiple rows into a slice of Entry
-func (v *FileVault) scanEntries(rows *sql.Rows) ([]Entry, error) {
+func (v *FileVault) scanEntries(rows *sql.Rows, withPassword bool) ([]Entry, error) {
 	var entries []Entry
 
 	for rows.Next() {
-		var e Entry
-		var encUsername, encPassword string
-		var url, notes sql.NullString
-		var createdAt, updatedAt sql.NullInt64
-
-		if err := rows.Scan(&e.ID, &e.Name, &encUsername, &encPassword, &url, &notes, &createdAt, &updatedAt); err != nil {
-			return nil, err
-		}
-
-		var err error
-		e.Username, err = v.decrypt(encUsername)
+		e, err := v.scanRow(rows, withPassword)
 		if err != nil {
 			return nil, err
 		}
-		e.Password, err = v.decrypt(encPassword)
-		if err != nil {
-			return nil, err
-		}
-
-		if url.Valid {
-			e.URL = url.String
-		}
-		if notes.Valid {
-			e.Notes = notes.String
-		}
-		if createdAt.Valid {
-			e.CreatedAt = createdAt.Int64
-		}
-		if updatedAt.Valid {
-			e.UpdatedAt = updatedAt.Int64
-		}
-
-		entries = append(entries, e)
+		entries = append(entries, *e)
 	}
 
 	return entries, rows.Err()
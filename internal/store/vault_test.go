@@ -0,0 +1,51 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testConfigDir points HOME at a fresh directory and returns the .lockin
+// directory in it. Argon2id is kept cheap so tests that derive keys are fast.
+func testConfigDir(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".lockin")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := "kdf_memory: 1024\nkdf_time: 1\nkdf_threads: 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// openTestVault opens the vault in the directory from testConfigDir
+func openTestVault(t *testing.T) *FileVault {
+	t.Helper()
+	v, err := NewFileVault()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { v.Close() })
+	return v
+}
+
+// newTestVault creates an unlocked vault holding a single entry, GitHub,
+// in a fresh home directory
+func newTestVault(t *testing.T, password string) *FileVault {
+	t.Helper()
+	testConfigDir(t)
+	v := openTestVault(t)
+	if err := v.Create(password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Add(Entry{Name: "GitHub", Username: "alice", Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,78 @@
+package strength
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Frequency lists from zxcvbn, most common first: passwords from leaks,
+// English words from TV and film subtitles, and US census names
+//
+//go:embed data/*.txt
+var data embed.FS
+
+// Dictionary names
+const (
+	dictPasswords = "passwords"
+	dictEnglish   = "english"
+	dictNames     = "names"
+	dictSurnames  = "surnames"
+	dictUser      = "user"
+)
+
+// dictionary maps each word to its rank, 1 being the most common
+type dictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+// dictionaries returns the embedded dictionaries, loaded on first use
+var dictionaries = sync.OnceValue(func() []dictionary {
+	var dicts []dictionary
+	for _, name := range []string{dictPasswords, dictEnglish, dictNames, dictSurnames} {
+		f, err := data.Open("data/" + name + ".txt")
+		if err != nil {
+			panic(err)
+		}
+
+		var words []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			words = append(words, scanner.Text())
+		}
+		f.Close()
+		dicts = append(dicts, newDictionary(name, words))
+	}
+	return dicts
+})
+
+// newDictionary ranks words in the order given
+func newDictionary(name string, words []string) dictionary {
+	d := dictionary{name: name, ranks: make(map[string]int, len(words))}
+	for _, word := range words {
+		if _, ok := d.ranks[word]; !ok {
+			d.ranks[word] = len(d.ranks) + 1
+		}
+	}
+	return d
+}
+
+// userDictionary builds a dictionary from the given inputs and the words in
+// them, so "github.com" also matches "github"
+func userDictionary(inputs []string) dictionary {
+	var words []string
+	for _, input := range inputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if input == "" {
+			continue
+		}
+		words = append(words, input)
+		words = append(words, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return newDictionary(dictUser, words)
+}
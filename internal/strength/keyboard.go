@@ -0,0 +1,99 @@
+package strength
+
+import (
+	"strings"
+	"sync"
+)
+
+// qwertyRows is the US keyboard, each key given as its unshifted and shifted
+// character. Every row below the first starts half a key to the right of the
+// one above it, which puts "q" at the same column as "1".
+var qwertyRows = []string{
+	"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+	"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+	"aA sS dD fF gG hH jJ kK lL ;: '\"",
+	"zZ xX cC vV bB nN mM ,< .> /?",
+}
+
+// keyPosition is the column and row of a key
+type keyPosition struct{ x, y int }
+
+// keyNeighbours are the offsets of the six keys around a key on a keyboard
+// with staggered rows: left, upper left, upper right, right, lower right and
+// lower left
+var keyNeighbours = []keyPosition{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+
+// layout is the keyboard indexed both ways
+type layout struct {
+	positions map[rune]keyPosition
+	keys      map[keyPosition]bool
+	shifted   map[rune]bool
+}
+
+var keyboard = sync.OnceValue(func() layout {
+	l := layout{
+		positions: make(map[rune]keyPosition),
+		keys:      make(map[keyPosition]bool),
+		shifted:   make(map[rune]bool),
+	}
+	for y, row := range qwertyRows {
+		offset := 0
+		if y > 0 {
+			offset = 1
+		}
+		for x, key := range strings.Fields(row) {
+			p := keyPosition{x + offset, y}
+			l.keys[p] = true
+			for i, r := range key {
+				l.positions[r] = p
+				l.shifted[r] = i > 0
+			}
+		}
+	}
+	return l
+})
+
+// keyDirection returns which neighbour of the key typing a the key typing b
+// is, as an index into keyNeighbours
+func keyDirection(a, b rune) (int, bool) {
+	l := keyboard()
+	pa, ok := l.positions[a]
+	if !ok {
+		return 0, false
+	}
+	pb, ok := l.positions[b]
+	if !ok {
+		return 0, false
+	}
+	for i, offset := range keyNeighbours {
+		if pa.x+offset.x == pb.x && pa.y+offset.y == pb.y {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// isShifted reports whether r is typed with Shift
+func isShifted(r rune) bool {
+	return keyboard().shifted[r]
+}
+
+// keyboardKeys returns the number of characters on the keyboard, each a
+// possible start of a pattern
+func keyboardKeys() int {
+	return len(keyboard().positions)
+}
+
+// keyboardDegree returns the average number of neighbours of a key
+func keyboardDegree() float64 {
+	l := keyboard()
+	neighbours := 0
+	for p := range l.keys {
+		for _, offset := range keyNeighbours {
+			if l.keys[keyPosition{p.x + offset.x, p.y + offset.y}] {
+				neighbours++
+			}
+		}
+	}
+	return float64(neighbours) / float64(len(l.keys))
+}
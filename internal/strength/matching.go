@@ -0,0 +1,544 @@
+package strength
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kinds of match
+const (
+	kindDictionary = "dictionary"
+	kindSpatial    = "spatial"
+	kindRepeat     = "repeat"
+	kindSequence   = "sequence"
+	kindDate       = "date"
+)
+
+// minWordLength is the shortest dictionary word matched
+const minWordLength = 3
+
+// match is a guessable part of a password, runes[i:j]
+type match struct {
+	kind    string
+	i, j    int
+	entropy float64
+
+	// Dictionary matches
+	dictionary string
+	rank       int
+	l33t       bool
+	reversed   bool
+
+	turns int    // keyboard patterns: changes of direction
+	block string // repeats: the repeated part
+	year  bool   // dates: a year on its own
+}
+
+// findMatches returns every guessable part of runes. bits is the entropy of
+// one random character of the whole password.
+func findMatches(runes []rune, user dictionary, bits float64) []match {
+	dicts := append(append([]dictionary(nil), dictionaries()...), user)
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, dicts)...)
+	matches = append(matches, reverseMatches(runes, dicts)...)
+	matches = append(matches, l33tMatches(runes, dicts)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, user, bits)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	return matches
+}
+
+// lower returns runes in lowercase, one rune for one
+func lower(runes []rune) []rune {
+	l := make([]rune, len(runes))
+	for i, r := range runes {
+		l[i] = unicode.ToLower(r)
+	}
+	return l
+}
+
+// dictionaryMatches finds dictionary words in runes, ignoring case
+func dictionaryMatches(runes []rune, dicts []dictionary) []match {
+	return wordMatches(runes, lower(runes), dicts)
+}
+
+// wordMatches finds the words of words in the dictionaries, scoring each
+// against the original runes at the same position
+func wordMatches(runes, words []rune, dicts []dictionary) []match {
+	var matches []match
+	for i := range words {
+		for j := i + minWordLength; j <= len(words); j++ {
+			word := string(words[i:j])
+			for _, d := range dicts {
+				rank, ok := d.ranks[word]
+				if !ok {
+					continue
+				}
+				matches = append(matches, match{
+					kind:       kindDictionary,
+					i:          i,
+					j:          j,
+					entropy:    math.Log2(float64(rank)) + uppercaseEntropy(runes[i:j]),
+					dictionary: d.name,
+					rank:       rank,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// reverseMatches finds dictionary words written backwards
+func reverseMatches(runes []rune, dicts []dictionary) []match {
+	n := len(runes)
+	reversed := make([]rune, n)
+	for i, r := range runes {
+		reversed[n-1-i] = r
+	}
+
+	matches := dictionaryMatches(reversed, dicts)
+	for k := range matches {
+		m := &matches[k]
+		m.i, m.j = n-m.j, n-m.i
+		m.reversed = true
+		m.entropy++
+	}
+	return matches
+}
+
+// l33tTable lists the letters each l33t character can stand for
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'7': {'l', 't'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'+': {'t'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// l33tMatches finds dictionary words written with l33t substitutions, such
+// as "p4ssw0rd"
+func l33tMatches(runes []rune, dicts []dictionary) []match {
+	lowered := lower(runes)
+
+	// Every way of reading the l33t characters present
+	subs := []map[rune]rune{{}}
+	for _, r := range lowered {
+		letters, ok := l33tTable[r]
+		if !ok || subs[0][r] != 0 {
+			continue
+		}
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range letters {
+				s := make(map[rune]rune, len(sub)+1)
+				for k, v := range sub {
+					s[k] = v
+				}
+				s[r] = letter
+				next = append(next, s)
+			}
+		}
+		subs = next
+	}
+	if len(subs[0]) == 0 {
+		return nil
+	}
+
+	var matches []match
+	for _, sub := range subs {
+		words := make([]rune, len(lowered))
+		for i, r := range lowered {
+			if letter, ok := sub[r]; ok {
+				words[i] = letter
+			} else {
+				words[i] = r
+			}
+		}
+
+		for _, m := range wordMatches(runes, words, dicts) {
+			token := lowered[m.i:m.j]
+			if string(token) == string(words[m.i:m.j]) {
+				// No substitution in this part; found by dictionaryMatches
+				continue
+			}
+			m.l33t = true
+			m.entropy += l33tEntropy(token, sub)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// l33tEntropy is the extra entropy of the substitutions in token: for each
+// one, the ways of choosing which occurrences of the letter were replaced
+func l33tEntropy(token []rune, sub map[rune]rune) float64 {
+	possibilities := 0.0
+	for l33t, letter := range sub {
+		substituted, unsubstituted := 0, 0
+		for _, r := range token {
+			switch r {
+			case l33t:
+				substituted++
+			case letter:
+				unsubstituted++
+			}
+		}
+		if substituted == 0 {
+			continue
+		}
+		for i := 1; i <= min(substituted, unsubstituted); i++ {
+			possibilities += binomial(substituted+unsubstituted, i)
+		}
+	}
+	if possibilities <= 1 {
+		return 1
+	}
+	return math.Log2(possibilities)
+}
+
+// uppercaseEntropy is the extra entropy of the capitalization of token.
+// Capitalizing the first or last letter, or every letter, adds one bit.
+func uppercaseEntropy(token []rune) float64 {
+	uppers, lowers := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			uppers++
+		case unicode.IsLower(r):
+			lowers++
+		}
+	}
+	if uppers == 0 {
+		return 0
+	}
+	if lowers == 0 || (uppers == 1 && (unicode.IsUpper(token[0]) || unicode.IsUpper(token[len(token)-1]))) {
+		return 1
+	}
+
+	possibilities := 0.0
+	for i := 0; i <= min(uppers, lowers); i++ {
+		possibilities += binomial(uppers+lowers, i)
+	}
+	return math.Log2(possibilities)
+}
+
+// spatialMatches finds runs of three or more adjacent keys, such as "qwerty"
+// or "zxcvbn"
+func spatialMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes)-2; {
+		j := i + 1
+		turns, lastDirection := 0, -1
+		shifted := 0
+		if isShifted(runes[i]) {
+			shifted++
+		}
+		for ; j < len(runes); j++ {
+			direction, ok := keyDirection(runes[j-1], runes[j])
+			if !ok {
+				break
+			}
+			if direction != lastDirection {
+				turns++
+				lastDirection = direction
+			}
+			if isShifted(runes[j]) {
+				shifted++
+			}
+		}
+
+		if j-i >= 3 {
+			matches = append(matches, match{
+				kind:    kindSpatial,
+				i:       i,
+				j:       j,
+				entropy: spatialEntropy(j-i, turns, shifted),
+				turns:   turns,
+			})
+		}
+		i = j
+	}
+	return matches
+}
+
+// spatialEntropy counts the keyboard patterns of the given length with at
+// most the given number of turns, plus the ways of shifting some keys
+func spatialEntropy(length, turns, shifted int) float64 {
+	starts, degree := float64(keyboardKeys()), keyboardDegree()
+
+	possibilities := 0.0
+	for l := 2; l <= length; l++ {
+		for t := 1; t <= min(turns, l-1); t++ {
+			possibilities += binomial(l-1, t-1) * starts * math.Pow(degree, float64(t))
+		}
+	}
+	entropy := math.Log2(possibilities)
+
+	if shifted > 0 {
+		unshifted := length - shifted
+		if unshifted == 0 {
+			entropy++
+		} else {
+			shiftings := 0.0
+			for i := 1; i <= min(shifted, unshifted); i++ {
+				shiftings += binomial(length, i)
+			}
+			entropy += math.Log2(shiftings)
+		}
+	}
+	return entropy
+}
+
+// repeatMatches finds characters repeated three or more times, such as
+// "aaa", and blocks repeated two or more times, such as "abcabc"
+func repeatMatches(runes []rune, user dictionary, bits float64) []match {
+	var matches []match
+	for i := 0; i < len(runes); {
+		bestLength, bestBlock, bestCount := 0, 0, 0
+		for block := 1; i+2*block <= len(runes); block++ {
+			count := 1
+			for i+(count+1)*block <= len(runes) &&
+				string(runes[i+count*block:i+(count+1)*block]) == string(runes[i:i+block]) {
+				count++
+			}
+			if count < 2 || (block == 1 && count < 3) {
+				continue
+			}
+			if count*block > bestLength {
+				bestLength, bestBlock, bestCount = count*block, block, count
+			}
+		}
+		if bestLength == 0 {
+			i++
+			continue
+		}
+
+		block := runes[i : i+bestBlock]
+		var entropy float64
+		if bestBlock == 1 {
+			entropy = math.Log2(float64(cardinality(block) * bestCount))
+		} else {
+			blockEntropy, _ := estimate(block, bits, user)
+			entropy = blockEntropy + math.Log2(float64(bestCount))
+		}
+		matches = append(matches, match{
+			kind:    kindRepeat,
+			i:       i,
+			j:       i + bestLength,
+			entropy: entropy,
+			block:   string(block),
+		})
+		i += bestLength
+	}
+	return matches
+}
+
+// sequenceOf returns which sequence r belongs to: lowercase, uppercase or digits
+func sequenceOf(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 1
+	case r >= 'A' && r <= 'Z':
+		return 2
+	case r >= '0' && r <= '9':
+		return 3
+	}
+	return 0
+}
+
+// sequenceMatches finds runs of three or more consecutive letters or digits,
+// ascending or descending, such as "abc" or "9876"
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes)-2; {
+		seq := sequenceOf(runes[i])
+		delta := runes[i+1] - runes[i]
+		if seq == 0 || sequenceOf(runes[i+1]) != seq || (delta != 1 && delta != -1) {
+			i++
+			continue
+		}
+
+		j := i + 2
+		for j < len(runes) && sequenceOf(runes[j]) == seq && runes[j]-runes[j-1] == delta {
+			j++
+		}
+		if j-i < 3 {
+			i++
+			continue
+		}
+
+		// Starting at the beginning of a sequence is the most obvious choice
+		var entropy float64
+		switch first := runes[i]; {
+		case first == 'a' || first == '1':
+			entropy = 1
+		case seq == 3:
+			entropy = math.Log2(10)
+		case seq == 1:
+			entropy = math.Log2(26)
+		default:
+			entropy = math.Log2(26) + 1
+		}
+		if delta < 0 {
+			entropy++
+		}
+		entropy += math.Log2(float64(j - i))
+
+		matches = append(matches, match{kind: kindSequence, i: i, j: j, entropy: entropy})
+		i = j
+	}
+	return matches
+}
+
+// Years a date is expected to fall in
+const (
+	minYear = 1900
+	maxYear = 2050
+)
+
+var (
+	// A day, month and year in some order, separated the same way twice
+	separatedDate = regexp.MustCompile(`(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})`)
+	digitRun      = regexp.MustCompile(`\d{4,8}`)
+)
+
+// dateMatches finds years and dates, with or without separators, such as
+// "1987", "13.05.1987" or "051387"
+func dateMatches(runes []rune) []match {
+	s := string(runes)
+
+	// Regexp offsets are in bytes; matches use runes
+	index := func(offset int) int {
+		return utf8.RuneCountInString(s[:offset])
+	}
+
+	var matches []match
+	for _, loc := range separatedDate.FindAllStringSubmatchIndex(s, -1) {
+		if s[loc[4]:loc[5]] != s[loc[8]:loc[9]] {
+			continue
+		}
+		parts := [3]string{s[loc[2]:loc[3]], s[loc[6]:loc[7]], s[loc[10]:loc[11]]}
+		if years, ok := validDate(parts); ok {
+			matches = append(matches, match{
+				kind:    kindDate,
+				i:       index(loc[0]),
+				j:       index(loc[1]),
+				entropy: math.Log2(31*12*float64(years)) + 2,
+			})
+		}
+	}
+
+	for _, loc := range digitRun.FindAllStringIndex(s, -1) {
+		digits := s[loc[0]:loc[1]]
+		start := index(loc[0])
+		for i := 0; i < len(digits); i++ {
+			for j := i + 4; j <= len(digits); j++ {
+				token := digits[i:j]
+				if len(token) == 4 {
+					if year, _ := strconv.Atoi(token); year >= minYear && year <= maxYear {
+						matches = append(matches, match{
+							kind:    kindDate,
+							i:       start + i,
+							j:       start + j,
+							entropy: math.Log2(maxYear - minYear + 1),
+							year:    true,
+						})
+					}
+				}
+				if years, ok := unseparatedDate(token); ok {
+					matches = append(matches, match{
+						kind:    kindDate,
+						i:       start + i,
+						j:       start + j,
+						entropy: math.Log2(31 * 12 * float64(years)),
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// unseparatedDate reports whether digits read as a date, such as "1387",
+// "13051987" or "19870513", and how many years its year format allows
+func unseparatedDate(digits string) (int, bool) {
+	for _, yearLength := range []int{4, 2} {
+		if len(digits) <= yearLength {
+			continue
+		}
+		for _, yearFirst := range []bool{false, true} {
+			year, rest := digits[len(digits)-yearLength:], digits[:len(digits)-yearLength]
+			if yearFirst {
+				year, rest = digits[:yearLength], digits[yearLength:]
+			}
+			if len(rest) < 2 || len(rest) > 4 {
+				continue
+			}
+			for k := 1; k <= 2 && k < len(rest); k++ {
+				if len(rest)-k > 2 {
+					continue
+				}
+				var parts [3]string
+				if yearFirst {
+					parts = [3]string{year, rest[:k], rest[k:]}
+				} else {
+					parts = [3]string{rest[:k], rest[k:], year}
+				}
+				if years, ok := validDate(parts); ok {
+					return years, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// validDate reports whether parts are a day, month and year in any common
+// order, and how many years its year format allows
+func validDate(parts [3]string) (int, bool) {
+	var n [3]int
+	for i, p := range parts {
+		n[i], _ = strconv.Atoi(p)
+	}
+
+	// Day-month-year, month-day-year or year-month-day
+	for _, order := range [][3]int{{0, 1, 2}, {1, 0, 2}, {2, 1, 0}} {
+		day, month, yearIndex := n[order[0]], n[order[1]], order[2]
+		if day < 1 || day > 31 || month < 1 || month > 12 {
+			continue
+		}
+		switch year := n[yearIndex]; len(parts[yearIndex]) {
+		case 4:
+			if year >= minYear && year <= maxYear {
+				return maxYear - minYear + 1, true
+			}
+		case 2:
+			return 100, true
+		}
+	}
+	return 0, false
+}
+
+// binomial returns n choose k
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
@@ -0,0 +1,222 @@
+// Package strength estimates how hard a password is to guess, in the style
+// of zxcvbn: the password is split into the cheapest combination of common
+// passwords, dictionary words, keyboard patterns, repeats, sequences and
+// dates, and whatever is left over is counted as random characters.
+package strength
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Score rates an estimate from VeryWeak to Strong
+type Score int
+
+const (
+	VeryWeak Score = iota
+	Weak
+	Fair
+	Good
+	Strong
+)
+
+// scoreThresholds are the bits of entropy needed for each score above VeryWeak
+var scoreThresholds = [...]float64{25, 40, 55, 70}
+
+var scoreLabels = [...]string{"Very weak", "Weak", "Fair", "Good", "Strong"}
+
+// String returns the score's display name
+func (s Score) String() string {
+	return scoreLabels[s]
+}
+
+// maxLength is how many characters are searched for patterns; the rest are
+// counted as random
+const maxLength = 100
+
+// Result is the estimated strength of a password
+type Result struct {
+	Entropy float64 // bits
+	Score   Score
+	Warning string // why the password is guessable, if it is not strong
+}
+
+// Weak reports whether the password is too guessable to use without
+// a second thought
+func (r Result) Weak() bool {
+	return r.Score <= Weak
+}
+
+// Check estimates the strength of password. userInputs are words an attacker
+// could know, such as the entry's name, username or site.
+func Check(password string, userInputs ...string) Result {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Result{Warning: "Enter a password"}
+	}
+
+	bits := math.Log2(float64(cardinality(runes)))
+	rest := 0
+	if len(runes) > maxLength {
+		rest = len(runes) - maxLength
+		runes = runes[:maxLength]
+	}
+
+	entropy, path := estimate(runes, bits, userDictionary(userInputs))
+	entropy += float64(rest) * bits
+
+	result := Result{Entropy: entropy, Score: scoreFor(entropy)}
+	if result.Score <= Fair {
+		result.Warning = warning(path, len(runes))
+	}
+	return result
+}
+
+// scoreFor maps bits of entropy to a score
+func scoreFor(entropy float64) Score {
+	score := VeryWeak
+	for _, threshold := range scoreThresholds {
+		if entropy >= threshold {
+			score++
+		}
+	}
+	return score
+}
+
+// estimate returns the lowest entropy of runes over every way of covering it
+// with matches and random characters worth bits each, and the matches used
+func estimate(runes []rune, bits float64, user dictionary) (float64, []match) {
+	matches := findMatches(runes, user, bits)
+
+	// best[k] is the lowest entropy of runes[:k], reached through last[k]
+	n := len(runes)
+	best := make([]float64, n+1)
+	last := make([]*match, n+1)
+	for k := 1; k <= n; k++ {
+		best[k] = best[k-1] + bits
+		for i := range matches {
+			m := &matches[i]
+			if m.j == k && best[m.i]+m.entropy < best[k] {
+				best[k] = best[m.i] + m.entropy
+				last[k] = m
+			}
+		}
+	}
+
+	var path []match
+	for k := n; k > 0; {
+		if m := last[k]; m != nil {
+			path = append(path, *m)
+			k = m.i
+		} else {
+			k--
+		}
+	}
+	return best[n], path
+}
+
+// cardinality returns the size of the smallest character set covering runes,
+// built from lowercase, uppercase, digits, ASCII symbols and everything else
+func cardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r <= unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	n := 0
+	for _, set := range []struct {
+		used bool
+		size int
+	}{
+		{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100},
+	} {
+		if set.used {
+			n += set.size
+		}
+	}
+	return n
+}
+
+// warning explains the longest guessable part of the password
+func warning(path []match, length int) string {
+	if len(path) == 0 {
+		if length < 10 {
+			return "Short passwords are easy to guess; add more characters"
+		}
+		return ""
+	}
+
+	m := path[0]
+	for _, p := range path[1:] {
+		if p.j-p.i > m.j-m.i {
+			m = p
+		}
+	}
+	whole := m.i == 0 && m.j == length
+
+	switch m.kind {
+	case kindDictionary:
+		return dictionaryWarning(m, whole)
+	case kindSpatial:
+		if m.turns == 1 {
+			return "Straight rows of keys are easy to guess"
+		}
+		return "Short keyboard patterns are easy to guess"
+	case kindRepeat:
+		if utf8.RuneCountInString(m.block) == 1 {
+			return `Repeats like "aaa" are easy to guess`
+		}
+		return `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+	case kindSequence:
+		return "Sequences like abc or 6543 are easy to guess"
+	case kindDate:
+		if m.year {
+			return "Years are easy to guess"
+		}
+		return "Dates are often easy to guess"
+	}
+	return ""
+}
+
+// dictionaryWarning explains a dictionary match
+func dictionaryWarning(m match, whole bool) string {
+	switch m.dictionary {
+	case dictPasswords:
+		if !whole || m.l33t || m.reversed {
+			return "This is similar to a commonly used password"
+		}
+		switch {
+		case m.rank <= 10:
+			return "This is a top-10 common password"
+		case m.rank <= 100:
+			return "This is a top-100 common password"
+		default:
+			return "This is a very common password"
+		}
+	case dictEnglish:
+		if whole {
+			return "A word by itself is easy to guess"
+		}
+		return "Common words are easy to guess"
+	case dictNames, dictSurnames:
+		if whole {
+			return "Names and surnames by themselves are easy to guess"
+		}
+		return "Common names and surnames are easy to guess"
+	case dictUser:
+		return "Avoid the entry's name, username or site in the password"
+	}
+	return ""
+}
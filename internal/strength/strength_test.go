@@ -0,0 +1,87 @@
+package strength
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	const (
+		aaa       = `Repeats like "aaa" are easy to guess`
+		abcabcabc = `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		similar   = "This is similar to a commonly used password"
+	)
+	tests := []struct {
+		password string
+		score    Score
+		warning  string
+	}{
+		{"", VeryWeak, "Enter a password"},
+
+		// Common passwords, with and without l33t and reversal
+		{"password", VeryWeak, "This is a top-10 common password"},
+		{"asdfgh", VeryWeak, "This is a top-100 common password"},
+		{"P@ssw0rd", VeryWeak, similar},
+		{"p4ssw0rd", VeryWeak, similar},
+		{"drowssap", VeryWeak, similar},
+		{"monkey1987", VeryWeak, similar},
+		{"Xk3!aaaaaa", Weak, similar},
+
+		// Words and names
+		{"elizabeth", VeryWeak, "Names and surnames by themselves are easy to guess"},
+		{"ilovejessica", VeryWeak, "Common names and surnames are easy to guess"},
+		{"correcthorsebatterystaple", Fair, "Common words are easy to guess"},
+		{"github", VeryWeak, "Avoid the entry's name, username or site in the password"},
+
+		// Keyboard walks
+		{"zxcvbnm,./", VeryWeak, "Straight rows of keys are easy to guess"},
+		{"poiu;lkj", VeryWeak, "Straight rows of keys are easy to guess"},
+		{"qwedcxz", VeryWeak, "Short keyboard patterns are easy to guess"},
+		{"wsxcde34", VeryWeak, "Short keyboard patterns are easy to guess"},
+
+		// Repeats are counted in characters, not bytes
+		{"aaaaaaaaaaaa", VeryWeak, aaa},
+		{"😀😀😀😀😀😀😀😀😀😀😀😀", VeryWeak, aaa},
+		{"abcabcabcabcabc", VeryWeak, abcabcabc},
+		{"🔒🔓🔒🔓🔒🔓🔒🔓", VeryWeak, abcabcabc},
+
+		// Sequences
+		{"abcdefgh", VeryWeak, "Sequences like abc or 6543 are easy to guess"},
+		{"9876543210", VeryWeak, "Sequences like abc or 6543 are easy to guess"},
+
+		// Dates
+		{"13/05/1987", VeryWeak, "Dates are often easy to guess"},
+		{"19870513", VeryWeak, "Dates are often easy to guess"},
+		{"1987", VeryWeak, "Years are easy to guess"},
+
+		// Random characters
+		{"jk8#Lp2q", Fair, "Short passwords are easy to guess; add more characters"},
+		{"Tr0ub4dor&3", Good, ""},
+		{"x7#Kq9!mZ2@vL4pW", Strong, ""},
+	}
+	for _, tt := range tests {
+		result := Check(tt.password, "github.com", "bob")
+		if result.Score != tt.score || result.Warning != tt.warning {
+			t.Errorf("Check(%q) = %s %q (%.1f bits), want %s %q",
+				tt.password, result.Score, result.Warning, result.Entropy, tt.score, tt.warning)
+		}
+	}
+}
+
+func TestScoreFor(t *testing.T) {
+	tests := []struct {
+		entropy float64
+		score   Score
+	}{
+		{0, VeryWeak},
+		{24.9, VeryWeak},
+		{25, Weak},
+		{40, Fair},
+		{55, Good},
+		{69.9, Good},
+		{70, Strong},
+		{200, Strong},
+	}
+	for _, tt := range tests {
+		if got := scoreFor(tt.entropy); got != tt.score {
+			t.Errorf("scoreFor(%v) = %s, want %s", tt.entropy, got, tt.score)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/otp"
+	"lockin/internal/store"
+	"lockin/internal/strength"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Indexes of the fixed inputs in an entryForm
+const (
+	inputName = iota
+	inputUsername
+	inputPassword
+	inputKeyFile
+	inputPassphrase
+	inputOTP
+	inputURL
+	inputTags
+	inputNotes
+)
+
+var (
+	entryInputLabels       = []string{"Name *", "Username", "Password *", "Private Key File *", "Key Passphrase", "One-Time Password", "URL", "Tags", "Notes"}
+	entryInputPlaceholders = []string{"Name", "Username", "Password", "~/.ssh/id_ed25519", "Only if the key has one", "otpauth:// URI or base32 secret (optional)", "URL (optional)", "work, personal (optional)", "Notes (optional)"}
+)
+
+// customFieldInput is one custom field in an entryForm. Fields from the
+// entry type's template keep their name, so only the value is editable.
+type customFieldInput struct {
+	name      textinput.Model
+	value     textinput.Model
+	concealed bool
+	template  bool
+}
+
+// entryForm is the add/edit form: the fixed entry inputs used by the entry
+// type followed by any number of custom fields
+type entryForm struct {
+	entryType store.EntryType
+	editing   bool // loaded from an existing entry
+	inputs    []textinput.Model
+	custom    []customFieldInput
+	focused   int // index into focusable()
+	generator *generatorPanel
+
+	acceptedWeak string // weak password the user chose to save anyway
+}
+
+// newEntryForm creates an empty form with the name input focused
+func newEntryForm() entryForm {
+	inputs := make([]textinput.Model, len(entryInputLabels))
+	for i := range inputs {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = entryInputPlaceholders[i]
+		inputs[i].CharLimit = 256
+		inputs[i].Width = 40
+		if i == inputPassword || i == inputPassphrase || i == inputOTP {
+			inputs[i].EchoMode = textinput.EchoPassword
+			inputs[i].EchoCharacter = '•'
+		}
+	}
+	inputs[inputName].Focus()
+	return entryForm{entryType: store.TypeLogin, inputs: inputs}
+}
+
+// newCustomFieldInput creates the inputs for one custom field
+func newCustomFieldInput(field store.CustomField) customFieldInput {
+	name := textinput.New()
+	name.Placeholder = "Field name"
+	name.CharLimit = 64
+	name.Width = 40
+	name.SetValue(field.Name)
+
+	value := textinput.New()
+	value.Placeholder = "Value"
+	value.CharLimit = 256
+	value.Width = 40
+	value.SetValue(field.Value)
+
+	c := customFieldInput{name: name, value: value}
+	c.setConcealed(field.Concealed)
+	return c
+}
+
+// setConcealed masks or unmasks the field's value
+func (c *customFieldInput) setConcealed(concealed bool) {
+	c.concealed = concealed
+	if concealed {
+		c.value.EchoMode = textinput.EchoPassword
+		c.value.EchoCharacter = '•'
+	} else {
+		c.value.EchoMode = textinput.EchoNormal
+	}
+}
+
+// fixed returns the indexes of the fixed inputs used by the form's entry type
+func (f *entryForm) fixed() []int {
+	fixed := []int{inputName}
+	if f.entryType.HasLogin() {
+		fixed = append(fixed, inputUsername, inputPassword)
+	}
+	if f.entryType == store.TypeSSHKey {
+		fixed = append(fixed, inputKeyFile, inputPassphrase)
+	}
+	if f.entryType.HasOTP() {
+		fixed = append(fixed, inputOTP)
+	}
+	if f.entryType.HasURL() {
+		fixed = append(fixed, inputURL)
+	}
+	return append(fixed, inputTags, inputNotes)
+}
+
+// label returns the label of a fixed input
+func (f *entryForm) label(i int) string {
+	// Only logins require a password
+	if i == inputPassword && f.entryType != store.TypeLogin {
+		return "Password"
+	}
+	// An existing SSH key is kept unless another file is given
+	if i == inputKeyFile && f.editing {
+		return "Replace Private Key File"
+	}
+	return entryInputLabels[i]
+}
+
+// inputs returns the field's editable inputs in tab order
+func (c *customFieldInput) inputs() []*textinput.Model {
+	if c.template {
+		return []*textinput.Model{&c.value}
+	}
+	return []*textinput.Model{&c.name, &c.value}
+}
+
+// focusable returns every input in tab order: the fixed inputs, then the
+// editable inputs of each custom field
+func (f *entryForm) focusable() []*textinput.Model {
+	fixed := f.fixed()
+	inputs := make([]*textinput.Model, 0, len(fixed)+2*len(f.custom))
+	for _, i := range fixed {
+		inputs = append(inputs, &f.inputs[i])
+	}
+	for i := range f.custom {
+		inputs = append(inputs, f.custom[i].inputs()...)
+	}
+	return inputs
+}
+
+// focusedCustom returns the index of the custom field holding the focus, or -1
+func (f *entryForm) focusedCustom() int {
+	i := f.focused - len(f.fixed())
+	if i < 0 {
+		return -1
+	}
+	for j := range f.custom {
+		n := len(f.custom[j].inputs())
+		if i < n {
+			return j
+		}
+		i -= n
+	}
+	return -1
+}
+
+// isTemplateField reports whether name is one of the template fields of the
+// form's entry type
+func (f *entryForm) isTemplateField(name string) bool {
+	for _, field := range f.entryType.Template() {
+		if strings.EqualFold(field.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addField appends a custom field, keeping the name fixed for template fields
+func (f *entryForm) addField(field store.CustomField) {
+	c := newCustomFieldInput(field)
+	c.template = field.Name != "" && f.isTemplateField(field.Name)
+	f.custom = append(f.custom, c)
+}
+
+// focus moves the focus to the input at index i in tab order
+func (f *entryForm) focus(i int) tea.Cmd {
+	inputs := f.focusable()
+	for _, input := range inputs {
+		input.Blur()
+	}
+	f.focused = (i + len(inputs)) % len(inputs)
+	inputs[f.focused].Focus()
+	return textinput.Blink
+}
+
+// reset clears every input, drops the custom fields and focuses the name
+func (f *entryForm) reset() {
+	for i := range f.inputs {
+		f.inputs[i].Reset()
+	}
+	f.entryType = store.TypeLogin
+	f.editing = false
+	f.custom = nil
+	f.generator = nil
+	f.acceptedWeak = ""
+	f.focus(0)
+}
+
+// start prepares an empty form for a new entry of the given type, with the
+// type's template fields
+func (f *entryForm) start(t store.EntryType) {
+	f.reset()
+	f.entryType = t
+	for _, field := range t.Template() {
+		f.addField(field)
+	}
+	f.focus(0)
+}
+
+// load fills the form with an existing entry
+func (f *entryForm) load(entry store.Entry) {
+	f.reset()
+	f.entryType = entry.Type
+	f.editing = true
+	f.inputs[inputName].SetValue(entry.Name)
+	f.inputs[inputUsername].SetValue(entry.Username)
+	f.inputs[inputPassword].SetValue(entry.Password)
+	f.acceptedWeak = entry.Password // only a changed password is checked
+	f.inputs[inputOTP].SetValue(entry.OTP)
+	f.inputs[inputURL].SetValue(entry.URL)
+	f.inputs[inputTags].SetValue(strings.Join(entry.Tags, ", "))
+	f.inputs[inputNotes].SetValue(entry.Notes)
+	for _, field := range entry.Fields {
+		f.addField(field)
+	}
+	f.focus(0)
+}
+
+// value returns the value of a fixed input
+func (f *entryForm) value(i int) string {
+	return f.inputs[i].Value()
+}
+
+// validate checks the required inputs
+func (f *entryForm) validate() error {
+	if f.value(inputName) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if f.entryType == store.TypeLogin && f.value(inputPassword) == "" {
+		return fmt.Errorf("password is required")
+	}
+	if f.entryType == store.TypeSSHKey && !f.editing && strings.TrimSpace(f.value(inputKeyFile)) == "" {
+		return fmt.Errorf("private key file is required")
+	}
+	if secret := strings.TrimSpace(f.value(inputOTP)); f.entryType.HasOTP() && secret != "" {
+		if _, err := otp.Parse(secret); err != nil {
+			return err
+		}
+	}
+	for i, c := range f.custom {
+		if strings.TrimSpace(c.name.Value()) == "" {
+			return fmt.Errorf("custom field %d needs a name", i+1)
+		}
+	}
+	return nil
+}
+
+// passwordStrength estimates the strength of the password input, counting
+// the name, username and URL as known to an attacker
+func (f *entryForm) passwordStrength() strength.Result {
+	return strength.Check(f.value(inputPassword), f.value(inputName), f.value(inputUsername), f.value(inputURL))
+}
+
+// confirmWeak returns an error the first time a weak password is saved, so
+// keeping it takes a second Enter
+func (f *entryForm) confirmWeak() error {
+	password := f.value(inputPassword)
+	if !f.entryType.HasLogin() || password == "" || password == f.acceptedWeak {
+		return nil
+	}
+	result := f.passwordStrength()
+	if !result.Weak() {
+		return nil
+	}
+	f.acceptedWeak = password
+	return weakPasswordError(result)
+}
+
+// apply copies the form's values onto entry, leaving the values of inputs
+// the entry type does not use untouched. A given SSH key file is read into
+// the entry.
+func (f *entryForm) apply(entry *store.Entry) error {
+	entry.Type = f.entryType
+	entry.Name = f.value(inputName)
+	if f.entryType.HasLogin() {
+		entry.Username = f.value(inputUsername)
+		entry.Password = f.value(inputPassword)
+	}
+	if f.entryType.HasOTP() {
+		entry.OTP = strings.TrimSpace(f.value(inputOTP))
+	}
+	if f.entryType.HasURL() {
+		entry.URL = f.value(inputURL)
+	}
+	entry.Tags = store.NormalizeTags(strings.Split(f.value(inputTags), ","))
+	entry.Notes = f.value(inputNotes)
+
+	entry.Fields = nil
+	for _, c := range f.custom {
+		entry.Fields = append(entry.Fields, store.CustomField{
+			Name:      strings.TrimSpace(c.name.Value()),
+			Value:     c.value.Value(),
+			Concealed: c.concealed,
+		})
+	}
+
+	// Read the key last so its public key fields replace the form's
+	if path := strings.TrimSpace(f.value(inputKeyFile)); f.entryType == store.TypeSSHKey && path != "" {
+		return store.LoadSSHKey(entry, expandPath(path), f.value(inputPassphrase))
+	}
+	return nil
+}
+
+// update handles navigation, custom field and generator keys, passing
+// anything else to the focused input
+func (f *entryForm) update(msg tea.Msg) tea.Cmd {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if f.generator != nil {
+			return f.updateGenerator(msg)
+		}
+
+		switch msg.String() {
+		case "tab", "down":
+			return f.focus(f.focused + 1)
+
+		case "shift+tab", "backtab", "up":
+			return f.focus(f.focused - 1)
+
+		case "ctrl+n":
+			// Add a custom field and focus its name
+			f.addField(store.CustomField{})
+			return f.focus(len(f.focusable()) - 2)
+
+		case "ctrl+x":
+			// Remove the focused custom field
+			if i := f.focusedCustom(); i >= 0 {
+				f.custom = append(f.custom[:i], f.custom[i+1:]...)
+				return f.focus(min(f.focused, len(f.focusable())-1))
+			}
+			return nil
+
+		case "ctrl+t":
+			// Toggle whether the focused custom field is concealed
+			if i := f.focusedCustom(); i >= 0 {
+				f.custom[i].setConcealed(!f.custom[i].concealed)
+			}
+			return nil
+
+		case "ctrl+g":
+			f.openGenerator()
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	inputs := f.focusable()
+	*inputs[f.focused], cmd = inputs[f.focused].Update(msg)
+	return cmd
+}
+
+// view renders the labelled inputs
+func (f entryForm) view() string {
+	var b strings.Builder
+
+	for j, i := range f.fixed() {
+		style := blurredStyle
+		if j == f.focused {
+			style = focusedStyle
+		}
+		b.WriteString(style.Render(f.label(i)))
+		b.WriteString("\n")
+		b.WriteString(f.inputs[i].View())
+		b.WriteString("\n")
+		if i == inputPassword && f.value(inputPassword) != "" {
+			b.WriteString(strengthMeter(f.passwordStrength()))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	n := 0
+	for i, c := range f.custom {
+		style := blurredStyle
+		if f.focusedCustom() == i {
+			style = focusedStyle
+		}
+		label := c.name.Value()
+		if !c.template {
+			n++
+			label = fmt.Sprintf("Custom Field %d", n)
+		}
+		if c.concealed {
+			label += " (concealed)"
+		}
+		b.WriteString(style.Render(label))
+		b.WriteString("\n")
+		if !c.template {
+			b.WriteString(c.name.View())
+			b.WriteString("\n")
+		}
+		b.WriteString(c.value.View())
+		b.WriteString("\n\n")
+	}
+
+	if f.generator != nil {
+		b.WriteString(f.generator.view())
+	}
+
+	return b.String()
+}
+
+// help returns the key help shown under the form
+func (f entryForm) help() string {
+	if f.generator != nil {
+		return generatorHelp
+	}
+	return formHelp
+}
+
+// formHelp is the key help shown under an entry form
+var formHelp = lipgloss.JoinVertical(lipgloss.Left,
+	"Tab/↓ next • Shift+Tab/↑ prev • Enter save • Esc cancel",
+	"Ctrl+N add field • Ctrl+X remove field • Ctrl+T conceal",
+	"Ctrl+G generate password",
+)
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/generator"
+	"lockin/internal/store"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// generatorPanel is the password generator opened from an entry form. It
+// previews a password for the chosen options until it is used or dismissed.
+type generatorPanel struct {
+	options generator.Options
+	target  int // index into the form's focusable() that receives the password
+	preview string
+	err     error
+}
+
+// generatorHelp is the key help shown while the generator is open
+var generatorHelp = lipgloss.JoinVertical(lipgloss.Left,
+	"Enter use • r regenerate • ←/→ length • Esc cancel",
+	"m password/passphrase • l/u/d/s a-z/A-Z/0-9/symbols",
+	"x exclude look-alikes",
+)
+
+// openGenerator shows the generator for the password input, or for the
+// focused custom field value of types without a password
+func (f *entryForm) openGenerator() {
+	target := -1
+	if f.entryType.HasLogin() {
+		for j, i := range f.fixed() {
+			if i == inputPassword {
+				target = j
+			}
+		}
+	} else if i := f.focusedCustom(); i >= 0 && f.focusable()[f.focused] == &f.custom[i].value {
+		target = f.focused
+	}
+	if target < 0 {
+		return
+	}
+
+	f.generator = &generatorPanel{options: store.GeneratorOptions(), target: target}
+	f.generator.regenerate()
+}
+
+// regenerate replaces the preview with a new password
+func (g *generatorPanel) regenerate() {
+	g.preview, g.err = generator.Generate(g.options)
+}
+
+// updateGenerator handles keys while the generator is open
+func (f *entryForm) updateGenerator(msg tea.KeyMsg) tea.Cmd {
+	g := f.generator
+	o := &g.options
+
+	switch msg.String() {
+	case "esc":
+		f.generator = nil
+		return nil
+
+	case "enter":
+		if g.err == nil {
+			f.focusable()[g.target].SetValue(g.preview)
+			f.generator = nil
+			return f.focus(g.target)
+		}
+		return nil
+
+	case "r", " ":
+		// Regenerate with the same options
+
+	case "m":
+		if o.Mode == generator.ModePassphrase {
+			o.Mode = generator.ModePassword
+		} else {
+			o.Mode = generator.ModePassphrase
+		}
+
+	case "left", "-":
+		if o.Mode == generator.ModePassphrase {
+			o.Words = max(o.Words-1, generator.MinWords)
+		} else {
+			o.Length = max(o.Length-1, generator.MinLength)
+		}
+
+	case "right", "+", "=":
+		if o.Mode == generator.ModePassphrase {
+			o.Words = min(o.Words+1, generator.MaxWords)
+		} else {
+			o.Length = min(o.Length+1, generator.MaxLength)
+		}
+
+	case "l":
+		o.Lowercase = !o.Lowercase
+	case "u":
+		o.Uppercase = !o.Uppercase
+	case "d":
+		o.Digits = !o.Digits
+	case "s":
+		o.Symbols = !o.Symbols
+	case "x":
+		o.ExcludeAmbiguous = !o.ExcludeAmbiguous
+
+	default:
+		return nil
+	}
+
+	g.regenerate()
+	return nil
+}
+
+// view renders the preview and the current options
+func (g generatorPanel) view() string {
+	var b strings.Builder
+
+	title := "Generate Password"
+	if g.options.Mode == generator.ModePassphrase {
+		title = "Generate Passphrase"
+	}
+	b.WriteString(focusedStyle.Render(title))
+	b.WriteString("\n")
+
+	if g.err != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", g.err.Error())))
+	} else {
+		b.WriteString(generatedStyle.Render(g.preview))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(blurredStyle.Render(fmt.Sprintf("%s • ~%.0f bits", g.options.Describe(), g.options.Entropy())))
+	b.WriteString("\n\n")
+
+	return b.String()
+}
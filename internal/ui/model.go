@@ -1,6 +1,10 @@
 package ui
 
 import (
+	"fmt"
+	"lockin/internal/audit"
+	"lockin/internal/otp"
+	"lockin/internal/secure"
 	"lockin/internal/store"
 	"strings"
 	"time"
@@ -23,6 +27,22 @@ func clearToastAfter(d time.Duration) tea.Cmd {
 	})
 }
 
+// IdleTickMsg is sent every second to check for inactivity
+type IdleTickMsg struct{}
+
+// idleTickInterval is how often inactivity is checked
+const idleTickInterval = time.Second
+
+// idleWarning is how long before auto-lock the countdown is shown
+const idleWarning = time.Minute
+
+// idleTick returns a command that sends the next IdleTickMsg
+func idleTick() tea.Cmd {
+	return tea.Tick(idleTickInterval, func(t time.Time) tea.Msg {
+		return IdleTickMsg{}
+	})
+}
+
 // setToast sets the toast message and returns a command to clear it
 func (m *Model) setToast(text string) tea.Cmd {
 	m.toastText = text
@@ -53,6 +73,13 @@ const (
 	ViewDetail
 	ViewEdit
 	ViewConfirmDelete
+	ViewChangePassword
+	ViewRecoveryKey
+	ViewRecover
+	ViewTrash
+	ViewChooseType
+	ViewAttachments
+	ViewAudit
 )
 
 // Model is the main application model
@@ -63,12 +90,17 @@ type Model struct {
 	err    error
 
 	// Login/Unlock state
-	masterInput textinput.Model
-	isNewUser   bool
+	masterInput     textinput.Model
+	confirmInput    textinput.Model
+	isNewUser       bool
+	requiresKeyfile bool
+	unlockRetryAt   time.Time // earliest next unlock attempt after repeated failures
+	acceptedWeak    string    // weak master password the user chose to keep
 
 	// Password list state
-	passwords []PasswordEntry
-	cursor    int
+	passwords  []PasswordEntry
+	cursor     int
+	typeFilter store.EntryType // only list entries of this type; empty lists all
 
 	// Search state
 	searching     bool
@@ -77,64 +109,112 @@ type Model struct {
 	searchCursor  int
 
 	// Add password state
-	addInputs  []textinput.Model
-	addFocused int
+	addForm    entryForm
+	typeCursor int // selected type when choosing what to add
 
 	// Edit password state
-	editInputs  []textinput.Model
-	editFocused int
-	editingID   int64
+	editForm  entryForm
+	editingID int64
+
+	// Change master password state
+	changeInputs  []textinput.Model
+	changeFocused int
+
+	// Recovery key state
+	recoveryKey     string // freshly generated key, shown once
+	recoveryInputs  []textinput.Model
+	recoveryFocused int
 
 	// Selected password for detail view
 	selected *PasswordEntry
+	otpKey   *otp.Key      // one-time password key of the selected entry, if any
+	breach   *store.Breach // last breach check of the selected entry's password, if any
+
+	// Password history panel in the detail view
+	showHistory     bool
+	history         []store.PasswordChange
+	historyCursor   int
+	historyRevealed *secure.Buffer // old password currently revealed, if any
+
+	// Attachments of the selected entry
+	attachments      []store.Attachment
+	attachmentCursor int
+	attachmentInput  textinput.Model // path to attach from or export to
+	attachmentAction attachmentAction
+	removeAttachment *store.Attachment // attachment awaiting confirmation of deletion
+
+	// Audit report; while set, the detail view returns to it
+	audit       *audit.Report
+	auditCursor int
 
 	// Delete confirmation state
 	confirmingDelete bool
 	deleteTarget     *PasswordEntry
 
+	// Trash state
+	trash       []PasswordEntry
+	trashCursor int
+	purgeTarget *PasswordEntry // entry awaiting confirmation of permanent deletion
+
 	// Storage
 	Vault *store.FileVault
 
 	// Notification
 	toastText string
+
+	// Auto-lock state
+	lastActivity time.Time
 }
 
-// PasswordEntry represents a stored password (UI representation)
+// PasswordEntry represents a stored password (UI representation). The
+// password itself is not kept here; it is decrypted from the vault on demand.
 type PasswordEntry struct {
 	ID       int64
+	Type     store.EntryType
 	Name     string
 	Username string
-	Password string
 	URL      string
 	Notes    string
+
+	DeletedAt int64               // set for entries in the trash
+	Tags      []string            // sorted tag names
+	Fields    []store.CustomField // concealed values are not loaded
+	HasOTP    bool
 }
 
 // ToStoreEntry converts a PasswordEntry to a store.Entry
 func (p PasswordEntry) ToStoreEntry() store.Entry {
 	return store.Entry{
 		ID:       p.ID,
+		Type:     p.Type,
 		Name:     p.Name,
 		Username: p.Username,
-		Password: p.Password,
 		URL:      p.URL,
 		Notes:    p.Notes,
+		Tags:     p.Tags,
+		Fields:   p.Fields,
 	}
 }
 
 // FromStoreEntry creates a PasswordEntry from a store.Entry
 func FromStoreEntry(e store.Entry) PasswordEntry {
 	return PasswordEntry{
-		ID:       e.ID,
-		Name:     e.Name,
-		Username: e.Username,
-		Password: e.Password,
-		URL:      e.URL,
-		Notes:    e.Notes,
+		ID:        e.ID,
+		Type:      e.Type,
+		Name:      e.Name,
+		Username:  e.Username,
+		URL:       e.URL,
+		Notes:     e.Notes,
+		DeletedAt: e.DeletedAt,
+		Tags:      e.Tags,
+		Fields:    e.Fields,
+		HasOTP:    e.HasOTP,
 	}
 }
 
-// New creates and returns a new Model with initial state
-func New() Model {
+// New creates and returns a new Model with initial state, or an error if
+// the vault cannot be opened
+func New() (Model, error) {
 	// Master password input
 	masterInput := textinput.New()
 	masterInput.Placeholder = "Enter master password..."
@@ -144,75 +224,101 @@ func New() Model {
 	masterInput.CharLimit = 128
 	masterInput.Width = 40
 
-	// Add password form inputs
-	addInputs := make([]textinput.Model, 5)
-	placeholders := []string{"Name", "Username", "Password", "URL (optional)", "Notes (optional)"}
-	for i := range addInputs {
-		addInputs[i] = textinput.New()
-		addInputs[i].Placeholder = placeholders[i]
-		addInputs[i].CharLimit = 256
-		addInputs[i].Width = 40
-		if i == 2 { // Password field
-			addInputs[i].EchoMode = textinput.EchoPassword
-			addInputs[i].EchoCharacter = '•'
-		}
-	}
-	addInputs[0].Focus()
+	// Master password confirmation input (vault creation only)
+	confirmInput := textinput.New()
+	confirmInput.Placeholder = "Repeat master password..."
+	confirmInput.EchoMode = textinput.EchoPassword
+	confirmInput.EchoCharacter = '•'
+	confirmInput.CharLimit = 128
+	confirmInput.Width = 40
 
 	// Search input
 	searchInput := textinput.New()
-	searchInput.Placeholder = "Search..."
+	searchInput.Placeholder = "Search... (tag:name filters by tag)"
 	searchInput.CharLimit = 128
 	searchInput.Width = 40
 
-	// Edit password form inputs (same structure as add)
-	editInputs := make([]textinput.Model, 5)
-	for i := range editInputs {
-		editInputs[i] = textinput.New()
-		editInputs[i].Placeholder = placeholders[i]
-		editInputs[i].CharLimit = 256
-		editInputs[i].Width = 40
-		if i == 2 { // Password field
-			editInputs[i].EchoMode = textinput.EchoPassword
-			editInputs[i].EchoCharacter = '•'
+	// Attachment path input
+	attachmentInput := textinput.New()
+	attachmentInput.CharLimit = 4096
+	attachmentInput.Width = 50
+
+	// Change master password inputs
+	changeInputs := make([]textinput.Model, 3)
+	changePlaceholders := []string{"Current master password", "New master password", "Repeat new master password"}
+	for i := range changeInputs {
+		changeInputs[i] = textinput.New()
+		changeInputs[i].Placeholder = changePlaceholders[i]
+		changeInputs[i].EchoMode = textinput.EchoPassword
+		changeInputs[i].EchoCharacter = '•'
+		changeInputs[i].CharLimit = 128
+		changeInputs[i].Width = 40
+	}
+
+	// Recover-with-key inputs
+	recoveryInputs := make([]textinput.Model, 3)
+	recoveryPlaceholders := []string{"XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", "New master password", "Repeat new master password"}
+	for i := range recoveryInputs {
+		recoveryInputs[i] = textinput.New()
+		recoveryInputs[i].Placeholder = recoveryPlaceholders[i]
+		recoveryInputs[i].CharLimit = 128
+		recoveryInputs[i].Width = 40
+		if i > 0 { // Password fields
+			recoveryInputs[i].EchoMode = textinput.EchoPassword
+			recoveryInputs[i].EchoCharacter = '•'
 		}
 	}
 
 	vault, err := store.NewFileVault()
 	if err != nil {
-		panic("failed to open vault: " + err.Error())
+		return Model{}, fmt.Errorf("opening vault: %w", err)
 	}
 
-	// Check if this is a new user (no existing credentials)
+	// Check if this is a new user (vault not created yet)
 	isNewUser := !vault.Exists()
 
 	return Model{
-		view:          ViewLogin,
-		isNewUser:     isNewUser,
-		masterInput:   masterInput,
-		addInputs:     addInputs,
-		editInputs:    editInputs,
-		searchInput:   searchInput,
-		passwords:     []PasswordEntry{},
-		searchResults: []PasswordEntry{},
-		Vault:         vault,
-	}
+		view:            ViewLogin,
+		isNewUser:       isNewUser,
+		requiresKeyfile: vault.RequiresKeyfile(),
+		unlockRetryAt:   time.Now().Add(vault.UnlockDelay()),
+		masterInput:     masterInput,
+		confirmInput:    confirmInput,
+		addForm:         newEntryForm(),
+		editForm:        newEntryForm(),
+		changeInputs:    changeInputs,
+		recoveryInputs:  recoveryInputs,
+		searchInput:     searchInput,
+		attachmentInput: attachmentInput,
+		passwords:       []PasswordEntry{},
+		searchResults:   []PasswordEntry{},
+		Vault:           vault,
+		lastActivity:    time.Now(),
+	}, nil
 }
 
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, idleTick())
 }
 
 // Update implements tea.Model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.lastActivity = time.Now()
 		switch msg.String() {
 		case "ctrl+c", "ctrl+q":
 			return m, tea.Quit
 		}
 
+	case IdleTickMsg:
+		if !m.Vault.IsLocked() && m.idleRemaining() <= 0 {
+			m.lock()
+			return m, tea.Batch(idleTick(), m.setToast("🔒 Vault locked after inactivity"))
+		}
+		return m, idleTick()
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -236,6 +342,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateEdit(msg)
 	case ViewConfirmDelete:
 		return m.updateConfirmDelete(msg)
+	case ViewChangePassword:
+		return m.updateChangePassword(msg)
+	case ViewRecoveryKey:
+		return m.updateRecoveryKey(msg)
+	case ViewRecover:
+		return m.updateRecover(msg)
+	case ViewTrash:
+		return m.updateTrash(msg)
+	case ViewChooseType:
+		return m.updateChooseType(msg)
+	case ViewAttachments:
+		return m.updateAttachments(msg)
+	case ViewAudit:
+		return m.updateAudit(msg)
 	}
 
 	return m, nil
@@ -257,37 +377,103 @@ func (m Model) View() string {
 		content = m.viewEdit()
 	case ViewConfirmDelete:
 		content = m.viewConfirmDelete()
+	case ViewChangePassword:
+		content = m.viewChangePassword()
+	case ViewRecoveryKey:
+		content = m.viewRecoveryKey()
+	case ViewRecover:
+		content = m.viewRecover()
+	case ViewTrash:
+		content = m.viewTrash()
+	case ViewChooseType:
+		content = m.viewChooseType()
+	case ViewAttachments:
+		content = m.viewAttachments()
+	case ViewAudit:
+		content = m.viewAudit()
 	default:
 		content = "Unknown view"
 	}
-	return m.withToastOverlay(content)
+	return m.withToastOverlay(m.withIdleOverlay(content))
+}
+
+// idleRemaining returns the time left before auto-lock, or a full timeout when disabled
+func (m Model) idleRemaining() time.Duration {
+	timeout := store.AutoLockTimeout()
+	if timeout == 0 {
+		return idleWarning + time.Second
+	}
+	return timeout - time.Since(m.lastActivity)
+}
+
+// lock locks the vault, drops all decrypted data held by the UI and returns to login
+func (m *Model) lock() {
+	m.Vault.Lock()
+	m.passwords = nil
+	m.cursor = 0
+	m.typeFilter = ""
+	m.selected = nil
+	m.otpKey = nil
+	m.breach = nil
+	m.closeHistory()
+	m.attachments = nil
+	m.attachmentCursor = 0
+	m.removeAttachment = nil
+	m.closeAttachmentInput()
+	m.audit = nil
+	m.auditCursor = 0
+	m.deleteTarget = nil
+	m.trash = nil
+	m.trashCursor = 0
+	m.purgeTarget = nil
+	m.searching = false
+	m.searchInput.Reset()
+	m.searchResults = nil
+	m.addForm.reset()
+	m.editForm.reset()
+	for i := range m.changeInputs {
+		m.changeInputs[i].Reset()
+	}
+	for i := range m.recoveryInputs {
+		m.recoveryInputs[i].Reset()
+	}
+	m.recoveryKey = ""
+	m.err = nil
+	m.view = ViewLogin
 }
 
-// refreshPasswords loads passwords from the vault into the model
+// refreshPasswords loads passwords of the filtered type from the vault into the model
 func (m *Model) refreshPasswords() error {
 	entries, err := m.Vault.List()
 	if err != nil {
 		return err
 	}
 
-	m.passwords = make([]PasswordEntry, len(entries))
-	for i, e := range entries {
-		m.passwords[i] = FromStoreEntry(e)
+	m.passwords = make([]PasswordEntry, 0, len(entries))
+	for _, e := range entries {
+		if m.typeFilter == "" || e.Type == m.typeFilter {
+			m.passwords = append(m.passwords, FromStoreEntry(e))
+		}
 	}
 	return nil
 }
 
-// filterPasswords returns passwords that match the search query (case-insensitive)
+// filterPasswords returns passwords that match the search query
+// (case-insensitive). "tag:" terms keep only entries carrying those tags.
 func (m *Model) filterPasswords(query string) []PasswordEntry {
 	if query == "" {
 		return m.passwords
 	}
 
-	query = strings.ToLower(query)
+	text, tags := store.ParseQuery(query)
+	text = strings.ToLower(text)
 	var results []PasswordEntry
 	for _, p := range m.passwords {
-		if strings.Contains(strings.ToLower(p.Name), query) ||
-			strings.Contains(strings.ToLower(p.Username), query) {
+		if !store.HasTags(p.Tags, tags) {
+			continue
+		}
+		if strings.Contains(strings.ToLower(p.Name), text) ||
+			strings.Contains(strings.ToLower(p.Username), text) {
 			results = append(results, p)
 		}
 	}
@@ -301,6 +487,23 @@ func (m *Model) getAutocompleteSuggestion() string {
 		return ""
 	}
 
+	// Complete a tag name after "tag:"
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return ""
+	}
+	if last := terms[len(terms)-1]; !strings.HasSuffix(query, " ") && strings.HasPrefix(strings.ToLower(last), "tag:") {
+		prefix := last[len("tag:"):]
+		for _, p := range m.passwords {
+			for _, tag := range p.Tags {
+				if len(tag) >= len(prefix) && strings.EqualFold(tag[:len(prefix)], prefix) {
+					return query + tag[len(prefix):]
+				}
+			}
+		}
+		return ""
+	}
+
 	queryLower := strings.ToLower(query)
 	for _, p := range m.passwords {
 		nameLower := strings.ToLower(p.Name)
@@ -311,6 +514,27 @@ func (m *Model) getAutocompleteSuggestion() string {
 	return ""
 }
 
+// withIdleOverlay shows a countdown during the last minute before auto-lock
+func (m Model) withIdleOverlay(bg string) string {
+	if m.Vault.IsLocked() {
+		return bg
+	}
+	remaining := m.idleRemaining()
+	if remaining > idleWarning {
+		return bg
+	}
+
+	warning := idleWarningStyle.Render(fmt.Sprintf("🔒 Locking in %ds", int(remaining.Round(time.Second).Seconds())))
+	return overlay.Composite(
+		warning,
+		bg,
+		overlay.Left,
+		overlay.Top,
+		2,
+		1,
+	)
+}
+
 func (m Model) withToastOverlay(bg string) string {
 	if m.toastText != "" {
 		toast := toastStyle.Render(m.toastText)
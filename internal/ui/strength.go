@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/strength"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// strengthColors colors the meter from very weak to strong
+var strengthColors = []lipgloss.Color{errorColor, errorColor, accentColor, secondaryColor, secondaryColor}
+
+// strengthMeter renders a bar for the estimated strength of a password,
+// followed by the reason it is guessable, if any
+func strengthMeter(result strength.Result) string {
+	filled := int(result.Score) + 1
+	bar := strings.Repeat("█", filled*2) + strings.Repeat("░", (int(strength.Strong)+1-filled)*2)
+	style := lipgloss.NewStyle().Foreground(strengthColors[result.Score])
+
+	meter := style.Render(bar) + blurredStyle.Render(fmt.Sprintf(" %s · ~%.0f bits", result.Score, result.Entropy))
+	if result.Warning != "" {
+		meter += "\n" + style.Width(52).Render(result.Warning)
+	}
+	return meter
+}
+
+// weakPasswordError asks the user to confirm a weak password by pressing
+// Enter again
+func weakPasswordError(result strength.Result) error {
+	return fmt.Errorf("%s password; press Enter again to use it anyway", result.Score)
+}
@@ -65,12 +65,32 @@ var (
 	normalItemStyle = lipgloss.NewStyle().
 			Foreground(textColor)
 
+	// One-time code in the detail view
+	otpCodeStyle = lipgloss.NewStyle().
+			Foreground(secondaryColor).
+			Bold(true)
+
+	// Generated password preview in entry forms
+	generatedStyle = lipgloss.NewStyle().
+			Foreground(secondaryColor).
+			Width(52)
+
+	// Tags shown after an entry
+	tagStyle = lipgloss.NewStyle().
+			Foreground(accentColor)
+
 	// Logo/header style
 	logoStyle = lipgloss.NewStyle().
 			Bold(true).
 			Foreground(primaryColor).
 			MarginBottom(2).Align(lipgloss.Center)
 
+	// Auto-lock countdown style
+	idleWarningStyle = lipgloss.NewStyle().
+				Foreground(accentColor).
+				Bold(true).
+				Padding(0, 1)
+
 	// Toast notification style
 	toastStyle = lipgloss.NewStyle().
 			Foreground(secondaryColor).
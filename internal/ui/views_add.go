@@ -10,52 +10,95 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// updateChooseType picks the type of a new entry before showing the add form
+func (m Model) updateChooseType(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k", "shift+tab", "backtab":
+			if m.typeCursor > 0 {
+				m.typeCursor--
+			}
+		case "down", "j", "tab":
+			if m.typeCursor < len(store.EntryTypes)-1 {
+				m.typeCursor++
+			}
+		case "enter":
+			m.addForm.start(store.EntryTypes[m.typeCursor])
+			m.view = ViewAdd
+			return m, textinput.Blink
+		case "esc", "q":
+			m.view = ViewList
+		}
+	}
+
+	return m, nil
+}
+
+func (m Model) viewChooseType() string {
+	var b strings.Builder
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render("✚ Add New Entry")
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	for i, t := range store.EntryTypes {
+		cursor := "  "
+		style := normalItemStyle
+		if m.typeCursor == i {
+			cursor = "▸ "
+			style = selectedItemStyle
+		}
+		b.WriteString(style.Render(fmt.Sprintf("%s%s %s", cursor, typeIcon(t), t.Label())))
+		b.WriteString("\n")
+	}
+
+	// Help
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("↑/↓ navigate • Enter select • Esc cancel"))
+
+	// Center the content
+	content := boxStyle.Width(60).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
 func (m Model) updateAdd(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// The generator handles its own Enter and Esc
+		if m.addForm.generator != nil {
+			return m, m.addForm.update(msg)
+		}
+
 		switch msg.String() {
 		case "esc":
+			m.addForm.reset()
 			m.view = ViewList
 			m.err = nil
 			return m, nil
 
-		case "tab", "down":
-			m.addInputs[m.addFocused].Blur()
-			m.addFocused = (m.addFocused + 1) % len(m.addInputs)
-			m.addInputs[m.addFocused].Focus()
-			return m, textinput.Blink
-
-		case "shift+tab", "up":
-			m.addInputs[m.addFocused].Blur()
-			m.addFocused--
-			if m.addFocused < 0 {
-				m.addFocused = len(m.addInputs) - 1
-			}
-			m.addInputs[m.addFocused].Focus()
-			return m, textinput.Blink
-
 		case "enter":
 			// Validate required fields
-			name := m.addInputs[0].Value()
-			username := m.addInputs[1].Value()
-			password := m.addInputs[2].Value()
-
-			if name == "" {
-				m.err = fmt.Errorf("name is required")
+			if err := m.addForm.validate(); err != nil {
+				m.err = err
 				return m, nil
 			}
-			if password == "" {
-				m.err = fmt.Errorf("password is required")
+			if err := m.addForm.confirmWeak(); err != nil {
+				m.err = err
 				return m, nil
 			}
 
 			// Create new entry and save to vault
-			entry := store.Entry{
-				Name:     name,
-				Username: username,
-				Password: password,
-				URL:      m.addInputs[3].Value(),
-				Notes:    m.addInputs[4].Value(),
+			var entry store.Entry
+			if err := m.addForm.apply(&entry); err != nil {
+				m.err = err
+				return m, nil
 			}
 
 			syncResult, err := m.Vault.Add(entry)
@@ -71,15 +114,15 @@ func (m Model) updateAdd(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Refresh passwords from vault
 			_ = m.refreshPasswords()
 
+			m.addForm.reset()
 			m.err = nil
 			m.view = ViewList
-			return m, m.setToast(formatSyncToast("Saved", name, syncResult))
+			return m, m.setToast(formatSyncToast("Saved", entry.Name, syncResult))
 		}
 	}
 
-	// Update the focused input
-	var cmd tea.Cmd
-	m.addInputs[m.addFocused], cmd = m.addInputs[m.addFocused].Update(msg)
+	// Update the form
+	cmd := m.addForm.update(msg)
 	return m, cmd
 }
 
@@ -91,23 +134,13 @@ func (m Model) viewAdd() string {
 		Bold(true).
 		Foreground(primaryColor).
 		MarginBottom(1).
-		Render("✚ Add New Password")
+		Render(fmt.Sprintf("✚ Add New %s", m.addForm.entryType.Label()))
 
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
 	// Input fields
-	labels := []string{"Name *", "Username", "Password *", "URL", "Notes"}
-	for i, input := range m.addInputs {
-		style := blurredStyle
-		if i == m.addFocused {
-			style = focusedStyle
-		}
-		b.WriteString(style.Render(labels[i]))
-		b.WriteString("\n")
-		b.WriteString(input.View())
-		b.WriteString("\n\n")
-	}
+	b.WriteString(m.addForm.view())
 
 	// Error message
 	if m.err != nil {
@@ -116,9 +149,9 @@ func (m Model) viewAdd() string {
 	}
 
 	// Help
-	b.WriteString(helpStyle.Render("Tab/↓ next • Shift+Tab/↑ prev • Enter save • Esc cancel"))
+	b.WriteString(helpStyle.Render(m.addForm.help()))
 
 	// Center the content
-	content := boxStyle.Width(50).Render(b.String())
+	content := boxStyle.Width(60).Render(b.String())
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/store"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// attachmentAction is what the path input in the attachments view is for
+type attachmentAction int
+
+const (
+	attachmentNone attachmentAction = iota
+	attachmentAdd
+	attachmentExport
+)
+
+func (m Model) updateAttachments(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		// Waiting for confirmation of a delete
+		if m.removeAttachment != nil {
+			switch msg.String() {
+			case "y", "Y":
+				name := m.removeAttachment.Name
+				syncResult, err := m.Vault.DeleteAttachment(m.removeAttachment.ID)
+				m.removeAttachment = nil
+				if err != nil {
+					return m, m.setToast("✗ Failed to delete attachment")
+				}
+				m.refreshAttachments()
+				return m, m.setToast(formatSyncToast("Deleted", name, syncResult))
+
+			case "n", "N", "esc", "q":
+				m.removeAttachment = nil
+			}
+			return m, nil
+		}
+
+		// Entering a path to attach from or export to
+		if m.attachmentAction != attachmentNone {
+			switch msg.String() {
+			case "esc":
+				m.closeAttachmentInput()
+				return m, nil
+
+			case "enter":
+				path := expandPath(strings.TrimSpace(m.attachmentInput.Value()))
+				if path == "" {
+					return m, nil
+				}
+				if m.attachmentAction == attachmentAdd {
+					return m.attachFile(path)
+				}
+				return m.exportAttachment(path)
+			}
+
+			var cmd tea.Cmd
+			m.attachmentInput, cmd = m.attachmentInput.Update(msg)
+			return m, cmd
+		}
+
+		switch msg.String() {
+		case "esc", "q":
+			m.attachmentCursor = 0
+			m.view = ViewDetail
+			return m, nil
+
+		case "up", "k":
+			if m.attachmentCursor > 0 {
+				m.attachmentCursor--
+			}
+
+		case "down", "j":
+			if m.attachmentCursor < len(m.attachments)-1 {
+				m.attachmentCursor++
+			}
+
+		case "n":
+			m.err = nil
+			m.attachmentAction = attachmentAdd
+			m.attachmentInput.Reset()
+			m.attachmentInput.Placeholder = "Path of the file to attach"
+			m.attachmentInput.Focus()
+			return m, textinput.Blink
+
+		case "s":
+			if len(m.attachments) == 0 {
+				return m, nil
+			}
+			m.err = nil
+			m.attachmentAction = attachmentExport
+			m.attachmentInput.Placeholder = "Path to save to"
+			m.attachmentInput.SetValue(m.attachments[m.attachmentCursor].Name)
+			m.attachmentInput.CursorEnd()
+			m.attachmentInput.Focus()
+			return m, textinput.Blink
+
+		case "d", "x":
+			if len(m.attachments) > 0 {
+				attachment := m.attachments[m.attachmentCursor]
+				m.removeAttachment = &attachment
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// attachFile attaches the file at path to the selected entry
+func (m Model) attachFile(path string) (tea.Model, tea.Cmd) {
+	syncResult, err := m.Vault.AttachFile(m.selected.ID, path)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	m.closeAttachmentInput()
+	m.refreshAttachments()
+	m.attachmentCursor = max(0, len(m.attachments)-1)
+	return m, m.setToast(formatSyncToast("Attached", filepath.Base(path), syncResult))
+}
+
+// exportAttachment saves the selected attachment to path, or into path when
+// it is a directory
+func (m Model) exportAttachment(path string) (tea.Model, tea.Cmd) {
+	attachment := m.attachments[m.attachmentCursor]
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, attachment.Name)
+	}
+	if err := m.Vault.ExportAttachment(attachment.ID, path); err != nil {
+		m.err = err
+		return m, nil
+	}
+	m.closeAttachmentInput()
+	return m, m.setToast(fmt.Sprintf("✓ Saved '%s' to %s", attachment.Name, path))
+}
+
+// closeAttachmentInput hides the path input of the attachments view
+func (m *Model) closeAttachmentInput() {
+	m.attachmentAction = attachmentNone
+	m.attachmentInput.Reset()
+	m.attachmentInput.Blur()
+	m.err = nil
+}
+
+// refreshAttachments loads the selected entry's attachments into the model
+func (m *Model) refreshAttachments() {
+	m.attachments = nil
+	if m.selected == nil {
+		return
+	}
+	attachments, err := m.Vault.Attachments(m.selected.ID)
+	if err != nil {
+		store.LogError("Failed to load attachments: %v", err)
+		return
+	}
+	m.attachments = attachments
+	if m.attachmentCursor >= len(m.attachments) {
+		m.attachmentCursor = max(0, len(m.attachments)-1)
+	}
+}
+
+// expandPath replaces a leading ~ with the user's home directory
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// formatSize renders a byte count as B, KB or MB
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+func (m Model) viewAttachments() string {
+	var b strings.Builder
+
+	if m.selected == nil {
+		return "No password selected"
+	}
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render(fmt.Sprintf("📎 %s attachments", m.selected.Name))
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	if len(m.attachments) == 0 {
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render("No attachments yet. Press 'n' to attach a file.")
+		b.WriteString(emptyMsg)
+		b.WriteString("\n")
+	}
+	for i, attachment := range m.attachments {
+		cursor := "  "
+		style := normalItemStyle
+		if m.attachmentCursor == i {
+			cursor = "▸ "
+			style = selectedItemStyle
+		}
+		b.WriteString(style.Render(cursor + attachment.Name))
+		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("  " + formatSize(attachment.Size)))
+		b.WriteString("\n")
+	}
+
+	// Path input
+	if m.attachmentAction != attachmentNone {
+		label := "Attach file"
+		if m.attachmentAction == attachmentExport {
+			label = "Save to"
+		}
+		b.WriteString("\n")
+		b.WriteString(focusedStyle.Render(label))
+		b.WriteString("\n")
+		b.WriteString(m.attachmentInput.View())
+		b.WriteString("\n")
+	}
+
+	// Error message
+	if m.err != nil {
+		b.WriteString("\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", m.err.Error())))
+		b.WriteString("\n")
+	}
+
+	// Delete confirmation
+	if m.removeAttachment != nil {
+		b.WriteString("\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Delete attachment '%s'? This cannot be undone.", m.removeAttachment.Name)))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Y confirm • N/Esc cancel"))
+	} else if m.attachmentAction != attachmentNone {
+		b.WriteString(helpStyle.Render("Enter confirm • Esc cancel"))
+	} else {
+		b.WriteString(helpStyle.Render("↑/↓ navigate • n attach file • s save • d delete • Esc/q back"))
+	}
+
+	// Center the content
+	content := boxStyle.Width(60).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/audit"
+	"lockin/internal/store"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m Model) updateAudit(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q":
+			m.audit = nil
+			m.auditCursor = 0
+			m.view = ViewList
+			return m, nil
+
+		case "up", "k":
+			if m.auditCursor > 0 {
+				m.auditCursor--
+			}
+
+		case "down", "j":
+			if m.audit != nil && m.auditCursor < len(m.audit.Findings)-1 {
+				m.auditCursor++
+			}
+
+		case "enter":
+			// Open the entry so the problem can be fixed; Esc comes back here
+			if m.audit != nil && len(m.audit.Findings) > 0 {
+				m.showDetail(FromStoreEntry(m.audit.Findings[m.auditCursor].Entry))
+			}
+
+		case "r":
+			if err := m.refreshAudit(); err != nil {
+				return m, m.setToast("✗ Failed to audit vault")
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// refreshAudit audits the vault again, keeping the cursor in range
+func (m *Model) refreshAudit() error {
+	report, err := audit.Run(m.Vault, store.AuditStaleAge())
+	if err != nil {
+		store.LogError("Failed to audit vault: %v", err)
+		return err
+	}
+	m.audit = report
+	if m.auditCursor >= len(report.Findings) {
+		m.auditCursor = max(0, len(report.Findings)-1)
+	}
+	return nil
+}
+
+func (m Model) viewAudit() string {
+	var b strings.Builder
+
+	if m.audit == nil {
+		return "No audit report"
+	}
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render("🩺 Vault Audit")
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	findings := m.audit.Findings
+
+	// Summary
+	counts := []string{fmt.Sprintf("Checked %d entries", m.audit.Checked)}
+	for _, kind := range audit.Kinds {
+		if n := m.audit.Count(kind); n > 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d", kind.Label(), n))
+		}
+	}
+	b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Width(56).Render(strings.Join(counts, " • ")))
+	b.WriteString("\n\n")
+
+	if len(findings) == 0 {
+		b.WriteString(successStyle.Render("✓ No problems found"))
+		b.WriteString("\n")
+	} else {
+		start, end := getVisibleWindow(m.auditCursor, len(findings), maxVisible)
+
+		// Show scroll indicator for items above
+		if start > 0 {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  ↑ %d more above\n", start)))
+		}
+
+		for i := start; i < end; i++ {
+			finding := findings[i]
+			cursor := "  "
+			style := normalItemStyle
+			if m.auditCursor == i {
+				cursor = "▸ "
+				style = selectedItemStyle
+			}
+
+			b.WriteString(style.Render(fmt.Sprintf("%s%s", cursor, finding.Entry.Name)))
+			b.WriteString(lipgloss.NewStyle().Foreground(accentColor).Render("  " + finding.Kind.Label()))
+			b.WriteString("\n")
+		}
+
+		// Show scroll indicator for items below
+		if end < len(findings) {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  ↓ %d more below\n", len(findings)-end)))
+		}
+
+		// Details of the selected finding
+		b.WriteString("\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(textColor).Width(56).Render(findings[m.auditCursor].Detail))
+		b.WriteString("\n")
+	}
+
+	// Help
+	b.WriteString(helpStyle.Render("↑/↓ navigate • Enter open entry • r re-run • Esc/q back"))
+
+	// Center the content
+	content := boxStyle.Width(60).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
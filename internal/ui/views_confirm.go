@@ -26,7 +26,15 @@ func (m Model) updateConfirmDelete(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.cursor--
 					}
 					m.view = ViewList
-					return m, m.setToast(formatSyncToast("Deleted", name, syncResult))
+					if m.audit != nil {
+						// Back to the audit the entry was opened from
+						if err := m.refreshAudit(); err != nil {
+							m.audit = nil
+						} else {
+							m.view = ViewAudit
+						}
+					}
+					return m, m.setToast(formatSyncToast("Moved to trash", name, syncResult))
 				}
 			}
 			m.view = ViewList
@@ -66,7 +74,7 @@ func (m Model) viewConfirmDelete() string {
 		b.WriteString(lipgloss.NewStyle().Foreground(textColor).Render(msg))
 		b.WriteString("\n\n")
 
-		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("This action cannot be undone."))
+		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("It will be moved to the trash, where it can be restored."))
 		b.WriteString("\n\n")
 	}
 
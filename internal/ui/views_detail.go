@@ -2,9 +2,14 @@ package ui
 
 import (
 	"fmt"
+	"lockin/internal/breach"
+	"lockin/internal/otp"
+	"lockin/internal/store"
 	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -14,22 +19,120 @@ func (m Model) updateDetail(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "esc", "q":
+			m.closeHistory()
 			m.selected = nil
+			m.otpKey = nil
+			m.breach = nil
+			m.attachments = nil
 			m.toastText = ""
 			m.view = ViewList
+			if m.audit != nil {
+				// Back to the audit, which may have changed after an edit
+				if err := m.refreshAudit(); err != nil {
+					m.audit = nil
+					return m, m.setToast("✗ Failed to audit vault")
+				}
+				m.view = ViewAudit
+			}
 			return m, nil
 
 		case "c":
-			if m.selected != nil {
-				if err := clipboard.WriteAll(m.selected.Password); err == nil {
+			if m.selected != nil && m.selected.Type.HasLogin() {
+				password, err := m.Vault.Password(m.selected.ID)
+				if err != nil {
+					return m, m.setToast("✗ Failed to decrypt password")
+				}
+				defer password.Destroy()
+				if err := clipboard.WriteAll(string(password.Bytes())); err == nil {
 					return m, m.setToast("✓ Password copied to clipboard")
 				}
 				return m, m.setToast("✗ Failed to copy password")
 			}
 			return m, nil
 
+		case "h":
+			if m.showHistory {
+				m.closeHistory()
+				return m, nil
+			}
+			if m.selected != nil && m.selected.Type.HasLogin() {
+				history, err := m.Vault.History(m.selected.ID)
+				if err != nil {
+					return m, m.setToast("✗ Failed to load password history")
+				}
+				m.history = history
+				m.historyCursor = 0
+				m.showHistory = true
+			}
+			return m, nil
+
+		case "up", "k":
+			if m.showHistory && m.historyCursor > 0 {
+				m.hideHistoryPassword()
+				m.historyCursor--
+			}
+			return m, nil
+
+		case "down", "j":
+			if m.showHistory && m.historyCursor < len(m.history)-1 {
+				m.hideHistoryPassword()
+				m.historyCursor++
+			}
+			return m, nil
+
+		case "r":
+			if !m.showHistory || len(m.history) == 0 {
+				return m, nil
+			}
+			if m.historyRevealed != nil {
+				m.hideHistoryPassword()
+				return m, nil
+			}
+			password, err := m.Vault.HistoryPassword(m.history[m.historyCursor].ID)
+			if err != nil {
+				return m, m.setToast("✗ Failed to decrypt password")
+			}
+			m.historyRevealed = password
+			return m, nil
+
+		case "y":
+			if !m.showHistory || len(m.history) == 0 {
+				return m, nil
+			}
+			password, err := m.Vault.HistoryPassword(m.history[m.historyCursor].ID)
+			if err != nil {
+				return m, m.setToast("✗ Failed to decrypt password")
+			}
+			defer password.Destroy()
+			if err := clipboard.WriteAll(string(password.Bytes())); err == nil {
+				return m, m.setToast("✓ Previous password copied to clipboard")
+			}
+			return m, m.setToast("✗ Failed to copy password")
+
+		case "o":
+			// Copy the current one-time code
+			if m.otpKey == nil {
+				return m, nil
+			}
+			if err := clipboard.WriteAll(m.otpKey.Code(time.Now())); err == nil {
+				return m, m.setToast("✓ One-time code copied to clipboard")
+			}
+			return m, m.setToast("✗ Failed to copy one-time code")
+
+		case "n":
+			// Advance an HOTP counter to the next code
+			if m.otpKey == nil || m.otpKey.Type != otp.TypeHOTP {
+				return m, nil
+			}
+			key, _, err := m.Vault.NextHOTP(m.selected.ID)
+			if err != nil {
+				return m, m.setToast("✗ Failed to advance counter")
+			}
+			m.otpKey = key
+			return m, nil
+
 		case "u":
-			if m.selected != nil {
+			if m.selected != nil && m.selected.Type.HasLogin() {
 				if err := clipboard.WriteAll(m.selected.Username); err == nil {
 					return m, m.setToast("✓ Username copied to clipboard")
 				}
@@ -37,27 +140,51 @@ func (m Model) updateDetail(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			// Copy a custom field's value
+			i := int(msg.String()[0] - '1')
+			if m.selected == nil || i >= len(m.selected.Fields) {
+				return m, nil
+			}
+			field := m.selected.Fields[i]
+			value, err := m.Vault.FieldValue(field.ID)
+			if err != nil {
+				return m, m.setToast("✗ Failed to decrypt field")
+			}
+			defer value.Destroy()
+			if err := clipboard.WriteAll(string(value.Bytes())); err == nil {
+				return m, m.setToast(fmt.Sprintf("✓ %s copied to clipboard", field.Name))
+			}
+			return m, m.setToast(fmt.Sprintf("✗ Failed to copy %s", field.Name))
+
+		case "a":
+			// Manage attachments
+			m.closeHistory()
+			if m.selected != nil {
+				m.attachmentCursor = 0
+				m.err = nil
+				m.view = ViewAttachments
+			}
+			return m, nil
+
 		case "e":
+			m.closeHistory()
 			if m.selected != nil {
-				// Populate edit inputs with current values
-				m.editInputs[0].SetValue(m.selected.Name)
-				m.editInputs[1].SetValue(m.selected.Username)
-				m.editInputs[2].SetValue(m.selected.Password)
-				m.editInputs[3].SetValue(m.selected.URL)
-				m.editInputs[4].SetValue(m.selected.Notes)
-
-				// Focus the first input
-				m.editFocused = 0
-				for i := range m.editInputs {
-					m.editInputs[i].Blur()
+				entry, err := m.Vault.Get(m.selected.ID)
+				if err != nil {
+					return m, m.setToast("✗ Failed to load entry")
 				}
-				m.editInputs[0].Focus()
 
+				// Populate the edit form with current values
+				m.editForm.load(*entry)
+				m.err = nil
 				m.view = ViewEdit
+				return m, textinput.Blink
 			}
 			return m, nil
 
 		case "d":
+			m.closeHistory()
 			if m.selected != nil {
 				m.deleteTarget = m.selected
 				m.view = ViewConfirmDelete
@@ -69,6 +196,52 @@ func (m Model) updateDetail(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// formatOTPCode splits a one-time code in two halves for readability
+func formatOTPCode(code string) string {
+	half := len(code) / 2
+	return code[:half] + " " + code[half:]
+}
+
+// showDetail opens the detail view for entry, loading its custom fields and attachments
+func (m *Model) showDetail(entry PasswordEntry) {
+	fields, err := m.Vault.Fields(entry.ID)
+	if err != nil {
+		store.LogError("Failed to load custom fields: %v", err)
+	}
+	entry.Fields = fields
+
+	m.otpKey = nil
+	if entry.HasOTP {
+		if m.otpKey, err = m.Vault.OTPKey(entry.ID); err != nil {
+			store.LogError("Failed to load one-time password key: %v", err)
+		}
+	}
+
+	if m.breach, err = m.Vault.EntryBreach(entry.ID); err != nil {
+		store.LogError("Failed to load breach check: %v", err)
+	}
+
+	m.selected = &entry
+	m.refreshAttachments()
+	m.view = ViewDetail
+}
+
+// hideHistoryPassword wipes the revealed previous password, if any
+func (m *Model) hideHistoryPassword() {
+	if m.historyRevealed != nil {
+		m.historyRevealed.Destroy()
+		m.historyRevealed = nil
+	}
+}
+
+// closeHistory hides the password history panel
+func (m *Model) closeHistory() {
+	m.hideHistoryPassword()
+	m.showHistory = false
+	m.history = nil
+	m.historyCursor = 0
+}
+
 func (m Model) viewDetail() string {
 	var b strings.Builder
 
@@ -83,7 +256,7 @@ func (m Model) viewDetail() string {
 		Bold(true).
 		Foreground(primaryColor).
 		MarginBottom(1).
-		Render(fmt.Sprintf("🔑 %s", entry.Name))
+		Render(fmt.Sprintf("%s %s", typeIcon(entry.Type), entry.Name))
 
 	b.WriteString(header)
 	b.WriteString("\n\n")
@@ -96,6 +269,13 @@ func (m Model) viewDetail() string {
 	valueStyle := lipgloss.NewStyle().
 		Foreground(textColor)
 
+	// Type, for anything other than a login
+	if entry.Type != store.TypeLogin {
+		b.WriteString(fieldStyle.Render("Type:"))
+		b.WriteString(valueStyle.Render(entry.Type.Label()))
+		b.WriteString("\n")
+	}
+
 	// Username
 	if entry.Username != "" {
 		b.WriteString(fieldStyle.Render("Username:"))
@@ -103,11 +283,38 @@ func (m Model) viewDetail() string {
 		b.WriteString("\n")
 	}
 
-	// Password (masked)
-	b.WriteString(fieldStyle.Render("Password:"))
-	masked := strings.Repeat("•", len(entry.Password))
-	b.WriteString(valueStyle.Render(masked))
-	b.WriteString("\n")
+	// Password (masked, never decrypted for display)
+	if entry.Type.HasLogin() {
+		b.WriteString(fieldStyle.Render("Password:"))
+		b.WriteString(valueStyle.Render(strings.Repeat("•", 8)))
+		b.WriteString("\n")
+
+		// Result of the last lookup in the local Pwned Passwords list
+		if m.breach != nil {
+			b.WriteString(fieldStyle.Render(""))
+			if m.breach.Count > 0 {
+				b.WriteString(lipgloss.NewStyle().Foreground(errorColor).Render("⚠ " + breach.Describe(m.breach.Count)))
+			} else {
+				checked := time.Unix(m.breach.CheckedAt, 0).Format("2006-01-02")
+				b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("✓ Not found in breaches (checked " + checked + ")"))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	// One-time code; the view is redrawn every second by the idle tick,
+	// which keeps the countdown live
+	if m.otpKey != nil {
+		now := time.Now()
+		b.WriteString(fieldStyle.Render("Code:"))
+		b.WriteString(otpCodeStyle.Render(formatOTPCode(m.otpKey.Code(now))))
+		if m.otpKey.Type == otp.TypeTOTP {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  %ds", int(m.otpKey.Remaining(now).Seconds()))))
+		} else {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  #%d", m.otpKey.Counter)))
+		}
+		b.WriteString("\n")
+	}
 
 	// URL
 	if entry.URL != "" {
@@ -116,6 +323,36 @@ func (m Model) viewDetail() string {
 		b.WriteString("\n")
 	}
 
+	// Tags
+	if len(entry.Tags) > 0 {
+		b.WriteString(fieldStyle.Render("Tags:"))
+		b.WriteString(tagStyle.Render(formatTags(entry.Tags)))
+		b.WriteString("\n")
+	}
+
+	// Custom fields, numbered for copying
+	for i, field := range entry.Fields {
+		value := field.Value
+		if field.Concealed {
+			value = strings.Repeat("•", 8)
+		}
+		label := field.Name + ":"
+		if i < 9 {
+			label = fmt.Sprintf("%d %s", i+1, label)
+		}
+		b.WriteString(fieldStyle.UnsetWidth().Render(label + " "))
+		b.WriteString(valueStyle.Render(value))
+		b.WriteString("\n")
+	}
+
+	// Attachments
+	for _, attachment := range m.attachments {
+		b.WriteString(fieldStyle.UnsetWidth().Render("📎 "))
+		b.WriteString(valueStyle.Render(attachment.Name))
+		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("  " + formatSize(attachment.Size)))
+		b.WriteString("\n")
+	}
+
 	// Notes
 	if entry.Notes != "" {
 		b.WriteString("\n")
@@ -125,11 +362,53 @@ func (m Model) viewDetail() string {
 		b.WriteString("\n")
 	}
 
+	// Password history
+	if m.showHistory {
+		b.WriteString("\n")
+		b.WriteString(fieldStyle.Render("History:"))
+		b.WriteString("\n")
+		if len(m.history) == 0 {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Italic(true).Render("No previous passwords"))
+			b.WriteString("\n")
+		}
+		for i, change := range m.history {
+			password := strings.Repeat("•", 8)
+			if i == m.historyCursor && m.historyRevealed != nil {
+				password = string(m.historyRevealed.Bytes())
+			}
+			line := fmt.Sprintf("%s  %s", time.Unix(change.ChangedAt, 0).Format("2006-01-02 15:04"), password)
+			if i == m.historyCursor {
+				b.WriteString(selectedItemStyle.Render("▸ " + line))
+			} else {
+				b.WriteString(normalItemStyle.Render("  " + line))
+			}
+			b.WriteString("\n")
+		}
+	}
+
 	// Help
 	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("c copy password • u copy username • e edit • d delete • Esc/q back"))
+	if m.showHistory {
+		b.WriteString(helpStyle.Render("↑/↓ select • r reveal • y copy old password • h hide history • Esc/q back"))
+	} else {
+		help := "a attachments • e edit • d delete • Esc/q back"
+		if entry.Type.HasLogin() {
+			help = "c copy password • u copy username • h history • " + help
+		}
+		if len(entry.Fields) > 0 {
+			help = "1-9 copy field • " + help
+		}
+		if m.otpKey != nil {
+			if m.otpKey.Type == otp.TypeHOTP {
+				help = "o copy code • n next code • " + help
+			} else {
+				help = "o copy code • " + help
+			}
+		}
+		b.WriteString(helpStyle.Render(help))
+	}
 
 	// Center the content
-	content := boxStyle.Width(50).Render(b.String())
+	content := boxStyle.Width(60).Render(b.String())
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
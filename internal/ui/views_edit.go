@@ -2,9 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"lockin/internal/store"
 	"strings"
 
-	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -12,72 +12,64 @@ import (
 func (m Model) updateEdit(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// The generator handles its own Enter and Esc
+		if m.editForm.generator != nil {
+			return m, m.editForm.update(msg)
+		}
+
 		switch msg.String() {
 		case "esc":
+			m.editForm.reset()
 			m.view = ViewDetail
 			m.err = nil
 			return m, nil
 
-		case "tab", "down":
-			m.editInputs[m.editFocused].Blur()
-			m.editFocused = (m.editFocused + 1) % len(m.editInputs)
-			m.editInputs[m.editFocused].Focus()
-			return m, textinput.Blink
-
-		case "shift+tab", "backtab", "up":
-			m.editInputs[m.editFocused].Blur()
-			m.editFocused--
-			if m.editFocused < 0 {
-				m.editFocused = len(m.editInputs) - 1
-			}
-			m.editInputs[m.editFocused].Focus()
-			return m, textinput.Blink
-
 		case "enter":
 			// Validate required fields
-			name := m.editInputs[0].Value()
-			username := m.editInputs[1].Value()
-			password := m.editInputs[2].Value()
-
-			if name == "" {
-				m.err = fmt.Errorf("name is required")
+			if err := m.editForm.validate(); err != nil {
+				m.err = err
 				return m, nil
 			}
-			if password == "" {
-				m.err = fmt.Errorf("password is required")
+			if err := m.editForm.confirmWeak(); err != nil {
+				m.err = err
 				return m, nil
 			}
 
-			// Update entry in vault
-			entry := m.selected.ToStoreEntry()
-			entry.Name = name
-			entry.Username = username
-			entry.Password = password
-			entry.URL = m.editInputs[3].Value()
-			entry.Notes = m.editInputs[4].Value()
+			// Update entry in vault, keeping what the form does not show
+			current, err := m.Vault.Get(m.selected.ID)
+			if err != nil {
+				m.err = fmt.Errorf("failed to load entry: %v", err)
+				return m, nil
+			}
+			entry := *current
+			if err := m.editForm.apply(&entry); err != nil {
+				m.err = err
+				return m, nil
+			}
 
 			syncResult, err := m.Vault.Update(entry)
 			if err != nil {
-				m.err = fmt.Errorf("failed to update: %v", err)
+				if err == store.ErrDuplicateEntry {
+					m.err = fmt.Errorf("an entry with this name already exists")
+				} else {
+					m.err = fmt.Errorf("failed to update: %v", err)
+				}
 				return m, nil
 			}
 
 			// Refresh passwords from vault
 			_ = m.refreshPasswords()
 
-			// Update selected entry
-			updated := FromStoreEntry(entry)
-			m.selected = &updated
-
+			// Show the updated entry
+			m.editForm.reset()
 			m.err = nil
-			m.view = ViewDetail
-			return m, m.setToast(formatSyncToast("Updated", name, syncResult))
+			m.showDetail(FromStoreEntry(entry))
+			return m, m.setToast(formatSyncToast("Updated", entry.Name, syncResult))
 		}
 	}
 
-	// Update the focused input
-	var cmd tea.Cmd
-	m.editInputs[m.editFocused], cmd = m.editInputs[m.editFocused].Update(msg)
+	// Update the form
+	cmd := m.editForm.update(msg)
 	return m, cmd
 }
 
@@ -89,23 +81,13 @@ func (m Model) viewEdit() string {
 		Bold(true).
 		Foreground(primaryColor).
 		MarginBottom(1).
-		Render("✎ Edit Password")
+		Render(fmt.Sprintf("✎ Edit %s", m.editForm.entryType.Label()))
 
 	b.WriteString(header)
 	b.WriteString("\n\n")
 
 	// Input fields
-	labels := []string{"Name *", "Username", "Password *", "URL", "Notes"}
-	for i, input := range m.editInputs {
-		style := blurredStyle
-		if i == m.editFocused {
-			style = focusedStyle
-		}
-		b.WriteString(style.Render(labels[i]))
-		b.WriteString("\n")
-		b.WriteString(input.View())
-		b.WriteString("\n\n")
-	}
+	b.WriteString(m.editForm.view())
 
 	// Error message
 	if m.err != nil {
@@ -114,9 +96,9 @@ func (m Model) viewEdit() string {
 	}
 
 	// Help
-	b.WriteString(helpStyle.Render("Tab/↓ next • Shift+Tab/↑ prev • Enter save • Esc cancel"))
+	b.WriteString(helpStyle.Render(m.editForm.help()))
 
 	// Center the content
-	content := boxStyle.Width(50).Render(b.String())
+	content := boxStyle.Width(60).Render(b.String())
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
 }
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"lockin/internal/store"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -28,17 +29,18 @@ func (m Model) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "enter":
 			if len(m.passwords) > 0 {
-				m.selected = &m.passwords[m.cursor]
-				m.view = ViewDetail
+				m.showDetail(m.passwords[m.cursor])
 			}
 		case "a":
-			// Reset add form
-			for i := range m.addInputs {
-				m.addInputs[i].Reset()
-			}
-			m.addFocused = 0
-			m.addInputs[0].Focus()
-			m.view = ViewAdd
+			// Choose the type of the new entry
+			m.typeCursor = 0
+			m.err = nil
+			m.view = ViewChooseType
+		case "f":
+			// Cycle the type filter: all, then each entry type
+			m.typeFilter = nextTypeFilter(m.typeFilter)
+			_ = m.refreshPasswords()
+			m.cursor = 0
 		case "/":
 			// Enter search mode
 			m.searching = true
@@ -55,15 +57,43 @@ func (m Model) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selected = &entry
 				m.view = ViewConfirmDelete
 			}
+		case "p":
+			// Change master password
+			for i := range m.changeInputs {
+				m.changeInputs[i].Reset()
+				m.changeInputs[i].Blur()
+			}
+			m.changeFocused = 0
+			m.changeInputs[0].Focus()
+			m.err = nil
+			m.view = ViewChangePassword
+			return m, textinput.Blink
+		case "t":
+			// Open the trash
+			if err := m.refreshTrash(); err != nil {
+				return m, m.setToast("✗ Failed to load trash")
+			}
+			m.trashCursor = 0
+			m.purgeTarget = nil
+			m.view = ViewTrash
+		case "A":
+			// Audit the vault
+			m.auditCursor = 0
+			if err := m.refreshAudit(); err != nil {
+				return m, m.setToast("✗ Failed to audit vault")
+			}
+			m.view = ViewAudit
+		case "K":
+			// Set up (or replace) the recovery key
+			m.recoveryKey = ""
+			m.err = nil
+			m.view = ViewRecoveryKey
 		case "r":
 			// Refresh passwords from vault
 			_ = m.refreshPasswords()
 		case "q":
 			// Lock vault and go back to login
-			m.Vault.Lock()
-			m.passwords = nil
-			m.cursor = 0
-			m.view = ViewLogin
+			m.lock()
 		}
 	}
 
@@ -84,11 +114,10 @@ func (m Model) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			// Select the current search result
 			if len(m.searchResults) > 0 && m.searchCursor < len(m.searchResults) {
-				m.selected = &m.searchResults[m.searchCursor]
+				m.showDetail(m.searchResults[m.searchCursor])
 				m.searching = false
 				m.searchInput.Reset()
 				m.searchResults = nil
-				m.view = ViewDetail
 			}
 			return m, nil
 
@@ -105,6 +134,17 @@ func (m Model) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.searchCursor--
 			}
 			return m, nil
+
+		case "right":
+			// Accept the autocomplete suggestion when the cursor is at the end
+			suggestion := m.getAutocompleteSuggestion()
+			if suggestion != "" && m.searchInput.Position() == len(m.searchInput.Value()) {
+				m.searchInput.SetValue(suggestion)
+				m.searchInput.CursorEnd()
+				m.searchResults = m.filterPasswords(suggestion)
+				m.searchCursor = 0
+				return m, nil
+			}
 		}
 	}
 
@@ -121,6 +161,49 @@ func (m Model) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// nextTypeFilter returns the type filter following current, wrapping back to
+// listing every type
+func nextTypeFilter(current store.EntryType) store.EntryType {
+	if current == "" {
+		return store.EntryTypes[0]
+	}
+	for i, t := range store.EntryTypes {
+		if t == current && i+1 < len(store.EntryTypes) {
+			return store.EntryTypes[i+1]
+		}
+	}
+	return ""
+}
+
+// typeIcon returns the icon shown for an entry type
+func typeIcon(t store.EntryType) string {
+	switch t {
+	case store.TypeNote:
+		return "📝"
+	case store.TypeCard:
+		return "💳"
+	case store.TypeAPIKey:
+		return "🔧"
+	case store.TypeIdentity:
+		return "👤"
+	case store.TypeDatabase:
+		return "💾"
+	case store.TypeSSHKey:
+		return "🔏"
+	default:
+		return "🔑"
+	}
+}
+
+// formatTags renders tag names as "#work #infra"
+func formatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "#" + tag
+	}
+	return strings.Join(formatted, " ")
+}
+
 // maxVisible is the maximum number of items to show at once
 const maxVisible = 10
 
@@ -151,12 +234,16 @@ func getVisibleWindow(cursor, total, maxItems int) (start, end int) {
 func (m Model) viewList() string {
 	var b strings.Builder
 
-	// Header
+	// Header, naming the type filter if any
+	title := "🔐 Your Passwords"
+	if m.typeFilter != "" {
+		title = fmt.Sprintf("🔐 Your Passwords · %s", m.typeFilter.Label())
+	}
 	header := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(primaryColor).
 		MarginBottom(1).
-		Render("🔐 Your Passwords")
+		Render(title)
 
 	b.WriteString(header)
 	b.WriteString("\n\n")
@@ -204,6 +291,12 @@ func (m Model) viewList() string {
 					line += fmt.Sprintf(" (%s)", entry.Username)
 				}
 				b.WriteString(style.Render(line))
+				if entry.Type != store.TypeLogin {
+					b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(" · " + entry.Type.Label()))
+				}
+				if len(entry.Tags) > 0 {
+					b.WriteString(tagStyle.Render(" " + formatTags(entry.Tags)))
+				}
 				b.WriteString("\n")
 			}
 
@@ -215,14 +308,18 @@ func (m Model) viewList() string {
 
 		// Help for search mode
 		b.WriteString("\n")
-		b.WriteString(helpStyle.Render("Tab/↓ next • Shift+Tab/↑ prev • Enter select • Esc cancel"))
+		b.WriteString(helpStyle.Render("↑/↓ navigate • → complete • Enter select • Esc cancel"))
 	} else {
 		// Normal list view
 		if len(m.passwords) == 0 {
+			msg := "No passwords stored yet. Press 'a' to add one."
+			if m.typeFilter != "" {
+				msg = fmt.Sprintf("No %s entries. Press 'f' to change the filter.", m.typeFilter.Label())
+			}
 			emptyMsg := lipgloss.NewStyle().
 				Foreground(mutedColor).
 				Italic(true).
-				Render("No passwords stored yet. Press 'a' to add one.")
+				Render(msg)
 			b.WriteString(emptyMsg)
 		} else {
 			start, end := getVisibleWindow(m.cursor, len(m.passwords), maxVisible)
@@ -246,6 +343,12 @@ func (m Model) viewList() string {
 					line += fmt.Sprintf(" (%s)", entry.Username)
 				}
 				b.WriteString(style.Render(line))
+				if entry.Type != store.TypeLogin {
+					b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(" · " + entry.Type.Label()))
+				}
+				if len(entry.Tags) > 0 {
+					b.WriteString(tagStyle.Render(" " + formatTags(entry.Tags)))
+				}
 				b.WriteString("\n")
 			}
 
@@ -257,7 +360,7 @@ func (m Model) viewList() string {
 
 		// Help
 		b.WriteString("\n")
-		b.WriteString(helpStyle.Render("↑/↓ navigate • Enter select • / search • a add • d delete • q lock"))
+		b.WriteString(helpStyle.Render("↑/↓ navigate • Enter select • / search • f filter type • a add • d delete • t trash • A audit • p master password • K recovery key • q lock"))
 	}
 
 	// Center the content
@@ -2,49 +2,156 @@ package ui
 
 import (
 	"fmt"
+	"lockin/internal/store"
+	"lockin/internal/strength"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 func (m Model) updateLogin(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.isNewUser {
+		return m.updateCreate(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "enter":
+			// Ignore attempts while throttled; the view shows the wait
+			if time.Now().Before(m.unlockRetryAt) {
+				return m, nil
+			}
+
 			password := m.masterInput.Value()
 
 			// Unlock the vault with the master password
 			if err := m.Vault.Unlock(password); err != nil {
-				m.err = err
+				switch err {
+				case store.ErrInvalidPassword:
+					m.err = fmt.Errorf("incorrect master password")
+				case store.ErrUnlockThrottled:
+					m.err = nil
+				default:
+					m.err = err
+				}
+				m.unlockRetryAt = time.Now().Add(m.Vault.UnlockDelay())
+				m.masterInput.Reset()
 				return m, nil
 			}
 
-			// Load passwords from the vault
-			if err := m.refreshPasswords(); err != nil {
-				// If decryption fails, the password was wrong
-				m.err = fmt.Errorf("incorrect master password or corrupted data")
-				m.Vault.Lock()
+			m.err = m.enterVault()
+			return m, nil
+
+		case "esc":
+			m.masterInput.Reset()
+			return m, nil
+
+		case "ctrl+r":
+			// Reset a forgotten master password with the recovery key
+			for i := range m.recoveryInputs {
+				m.recoveryInputs[i].Reset()
+				m.recoveryInputs[i].Blur()
+			}
+			m.recoveryFocused = 0
+			m.recoveryInputs[0].Focus()
+			m.masterInput.Reset()
+			m.err = nil
+			m.view = ViewRecover
+			return m, textinput.Blink
+		}
+	}
+
+	var cmd tea.Cmd
+	m.masterInput, cmd = m.masterInput.Update(msg)
+	return m, cmd
+}
+
+// updateCreate handles the create-vault flow, which asks for the password twice
+func (m Model) updateCreate(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter":
+			// First field: require a password, then move to confirmation
+			if m.masterInput.Focused() {
+				if m.masterInput.Value() == "" {
+					m.err = fmt.Errorf("master password is required")
+					return m, nil
+				}
+				if password := m.masterInput.Value(); password != m.acceptedWeak {
+					if result := strength.Check(password); result.Weak() {
+						m.acceptedWeak = password
+						m.err = weakPasswordError(result)
+						return m, nil
+					}
+				}
+				m.err = nil
+				m.masterInput.Blur()
+				m.confirmInput.Focus()
+				return m, textinput.Blink
+			}
+
+			password := m.masterInput.Value()
+			if m.confirmInput.Value() != password {
+				m.err = fmt.Errorf("passwords do not match")
+				m.confirmInput.Reset()
 				return m, nil
 			}
 
-			m.err = nil
-			m.view = ViewList
+			if err := m.Vault.Create(password); err != nil {
+				m.err = fmt.Errorf("failed to create vault: %v", err)
+				return m, nil
+			}
+
+			m.isNewUser = false
+			m.acceptedWeak = ""
+			m.requiresKeyfile = m.Vault.RequiresKeyfile()
+			m.confirmInput.Reset()
+			m.confirmInput.Blur()
+			m.masterInput.Focus()
 			m.masterInput.Reset()
+
+			// Offer a recovery key before showing the (empty) vault
+			m.err = nil
+			m.recoveryKey = ""
+			m.view = ViewRecoveryKey
 			return m, nil
 
 		case "esc":
+			m.err = nil
 			m.masterInput.Reset()
-			return m, nil
+			m.confirmInput.Reset()
+			m.confirmInput.Blur()
+			m.masterInput.Focus()
+			return m, textinput.Blink
 		}
 	}
 
 	var cmd tea.Cmd
-	m.masterInput, cmd = m.masterInput.Update(msg)
+	if m.confirmInput.Focused() {
+		m.confirmInput, cmd = m.confirmInput.Update(msg)
+	} else {
+		m.masterInput, cmd = m.masterInput.Update(msg)
+	}
 	return m, cmd
 }
 
+// enterVault loads passwords from the unlocked vault and switches to the list
+func (m *Model) enterVault() error {
+	m.masterInput.Reset()
+	if err := m.refreshPasswords(); err != nil {
+		m.Vault.Lock()
+		return fmt.Errorf("failed to decrypt vault data: %v", err)
+	}
+
+	m.view = ViewList
+	return nil
+}
+
 func (m Model) viewLogin() string {
 	var b strings.Builder
 
@@ -69,18 +176,55 @@ func (m Model) viewLogin() string {
 	b.WriteString("\n")
 	b.WriteString(m.masterInput.View())
 	b.WriteString("\n")
+	if m.isNewUser && m.masterInput.Value() != "" {
+		b.WriteString(strengthMeter(strength.Check(m.masterInput.Value())))
+		b.WriteString("\n")
+	}
+
+	// Confirmation input
+	if m.isNewUser {
+		b.WriteString("\n")
+		b.WriteString(labelStyle.Render("Confirm Password"))
+		b.WriteString("\n")
+		b.WriteString(m.confirmInput.View())
+		b.WriteString("\n")
+	}
+
+	// Keyfile status
+	keyfile := store.KeyfilePath()
+	if m.isNewUser && keyfile != "" {
+		b.WriteString("\n")
+		b.WriteString(blurredStyle.Render(fmt.Sprintf("🗝 Keyfile %s will be required to unlock", keyfile)))
+		b.WriteString("\n")
+	} else if m.requiresKeyfile {
+		b.WriteString("\n")
+		if keyfile == "" {
+			b.WriteString(errorStyle.Render("🗝 Keyfile required but none configured"))
+		} else {
+			b.WriteString(blurredStyle.Render(fmt.Sprintf("🗝 Keyfile required: %s", keyfile)))
+		}
+		b.WriteString("\n")
+	}
 
 	// Error message
 	if m.err != nil {
 		b.WriteString("\n")
 		b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", m.err.Error())))
+		b.WriteString("\n")
+	}
+
+	// Throttling after repeated failures
+	if wait := time.Until(m.unlockRetryAt); !m.isNewUser && wait > 0 {
+		b.WriteString("\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("⏳ Too many failed attempts, try again in %s", wait.Round(time.Second))))
+		b.WriteString("\n")
 	}
 
 	// Help text
 	if m.isNewUser {
-		b.WriteString(helpStyle.Render("Press Enter to create vault • Ctrl+C to quit"))
+		b.WriteString(helpStyle.Render("Press Enter to continue • Esc to start over • Ctrl+C to quit"))
 	} else {
-		b.WriteString(helpStyle.Render("Press Enter to unlock • Ctrl+C to quit"))
+		b.WriteString(helpStyle.Render("Press Enter to unlock • Ctrl+R forgot password • Ctrl+C to quit"))
 	}
 
 	// Center the content
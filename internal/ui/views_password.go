@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/store"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m Model) updateChangePassword(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			for i := range m.changeInputs {
+				m.changeInputs[i].Reset()
+			}
+			m.view = ViewList
+			m.err = nil
+			return m, nil
+
+		case "tab", "down":
+			m.changeInputs[m.changeFocused].Blur()
+			m.changeFocused = (m.changeFocused + 1) % len(m.changeInputs)
+			m.changeInputs[m.changeFocused].Focus()
+			return m, textinput.Blink
+
+		case "shift+tab", "backtab", "up":
+			m.changeInputs[m.changeFocused].Blur()
+			m.changeFocused--
+			if m.changeFocused < 0 {
+				m.changeFocused = len(m.changeInputs) - 1
+			}
+			m.changeInputs[m.changeFocused].Focus()
+			return m, textinput.Blink
+
+		case "enter":
+			current := m.changeInputs[0].Value()
+			newPassword := m.changeInputs[1].Value()
+			confirm := m.changeInputs[2].Value()
+
+			if newPassword == "" {
+				m.err = fmt.Errorf("new master password is required")
+				return m, nil
+			}
+			if newPassword != confirm {
+				m.err = fmt.Errorf("new passwords do not match")
+				return m, nil
+			}
+
+			syncResult, err := m.Vault.ChangeMasterPassword(current, newPassword)
+			if err != nil {
+				if err == store.ErrInvalidPassword {
+					m.err = fmt.Errorf("current master password is incorrect")
+				} else {
+					m.err = fmt.Errorf("failed to change master password: %v", err)
+				}
+				return m, nil
+			}
+
+			for i := range m.changeInputs {
+				m.changeInputs[i].Reset()
+			}
+			m.err = nil
+			m.view = ViewList
+			return m, m.setToast(formatSyncToast("Changed", "master password", syncResult))
+		}
+	}
+
+	// Update the focused input
+	var cmd tea.Cmd
+	m.changeInputs[m.changeFocused], cmd = m.changeInputs[m.changeFocused].Update(msg)
+	return m, cmd
+}
+
+func (m Model) viewChangePassword() string {
+	var b strings.Builder
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render("🔒 Change Master Password")
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	// Input fields
+	labels := []string{"Current Password", "New Password", "Confirm New Password"}
+	for i, input := range m.changeInputs {
+		style := blurredStyle
+		if i == m.changeFocused {
+			style = focusedStyle
+		}
+		b.WriteString(style.Render(labels[i]))
+		b.WriteString("\n")
+		b.WriteString(input.View())
+		b.WriteString("\n\n")
+	}
+
+	// Error message
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", m.err.Error())))
+		b.WriteString("\n")
+	}
+
+	// Help
+	b.WriteString(helpStyle.Render("Tab/↓ next • Shift+Tab/↑ prev • Enter change • Esc cancel"))
+
+	// Center the content
+	content := boxStyle.Width(50).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
@@ -0,0 +1,215 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/store"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m Model) updateRecoveryKey(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		// Key is on screen: wait for the user to confirm they saved it
+		if m.recoveryKey != "" {
+			if msg.String() == "enter" {
+				m.recoveryKey = ""
+				m.view = ViewList
+				return m, m.setToast("✓ Recovery key saved")
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "y", "Y":
+			key, err := m.Vault.GenerateRecoveryKey()
+			if err != nil {
+				m.err = fmt.Errorf("failed to generate recovery key: %v", err)
+				return m, nil
+			}
+			m.err = nil
+			m.recoveryKey = key
+			return m, nil
+
+		case "n", "N", "esc", "q":
+			m.err = nil
+			m.view = ViewList
+			return m, nil
+		}
+	}
+
+	return m, nil
+}
+
+func (m Model) viewRecoveryKey() string {
+	var b strings.Builder
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render("🛟 Recovery Key")
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	textStyle := lipgloss.NewStyle().Foreground(textColor)
+	mutedStyle := lipgloss.NewStyle().Foreground(mutedColor)
+
+	if m.recoveryKey != "" {
+		b.WriteString(textStyle.Render("Write this key down and keep it somewhere safe. It will not be shown again."))
+		b.WriteString("\n\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(accentColor).Bold(true).Render(m.recoveryKey))
+		b.WriteString("\n\n")
+		b.WriteString(mutedStyle.Render("Use it with Ctrl+R on the unlock screen or `lockin recover` to set a new master password."))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Enter I have saved it"))
+	} else {
+		b.WriteString(textStyle.Render("A recovery key lets you set a new master password if you forget yours."))
+		b.WriteString("\n\n")
+		if m.Vault.HasRecoveryKey() {
+			b.WriteString(mutedStyle.Render("Generating a new key invalidates the existing one."))
+			b.WriteString("\n\n")
+		}
+
+		// Options
+		b.WriteString(lipgloss.NewStyle().Foreground(secondaryColor).Bold(true).Render("[Y]"))
+		b.WriteString(textStyle.Render(" Generate key"))
+		b.WriteString("    ")
+		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Bold(true).Render("[N]"))
+		b.WriteString(textStyle.Render(" Skip"))
+		b.WriteString("\n")
+
+		// Error message
+		if m.err != nil {
+			b.WriteString("\n")
+			b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", m.err.Error())))
+			b.WriteString("\n")
+		}
+
+		b.WriteString(helpStyle.Render("Y generate • N/Esc skip"))
+	}
+
+	// Center the content
+	content := boxStyle.Width(60).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m Model) updateRecover(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			for i := range m.recoveryInputs {
+				m.recoveryInputs[i].Reset()
+			}
+			m.err = nil
+			m.view = ViewLogin
+			return m, textinput.Blink
+
+		case "tab", "down":
+			m.recoveryInputs[m.recoveryFocused].Blur()
+			m.recoveryFocused = (m.recoveryFocused + 1) % len(m.recoveryInputs)
+			m.recoveryInputs[m.recoveryFocused].Focus()
+			return m, textinput.Blink
+
+		case "shift+tab", "backtab", "up":
+			m.recoveryInputs[m.recoveryFocused].Blur()
+			m.recoveryFocused--
+			if m.recoveryFocused < 0 {
+				m.recoveryFocused = len(m.recoveryInputs) - 1
+			}
+			m.recoveryInputs[m.recoveryFocused].Focus()
+			return m, textinput.Blink
+
+		case "enter":
+			recoveryKey := m.recoveryInputs[0].Value()
+			newPassword := m.recoveryInputs[1].Value()
+			confirm := m.recoveryInputs[2].Value()
+
+			if recoveryKey == "" {
+				m.err = fmt.Errorf("recovery key is required")
+				return m, nil
+			}
+			if newPassword == "" {
+				m.err = fmt.Errorf("new master password is required")
+				return m, nil
+			}
+			if newPassword != confirm {
+				m.err = fmt.Errorf("new passwords do not match")
+				return m, nil
+			}
+
+			syncResult, err := m.Vault.Recover(recoveryKey, newPassword)
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+
+			for i := range m.recoveryInputs {
+				m.recoveryInputs[i].Reset()
+			}
+			if m.err = m.enterVault(); m.err != nil {
+				return m, nil
+			}
+			return m, m.setToast(formatSyncToast("Reset", "master password", syncResult))
+		}
+	}
+
+	// Update the focused input
+	var cmd tea.Cmd
+	m.recoveryInputs[m.recoveryFocused], cmd = m.recoveryInputs[m.recoveryFocused].Update(msg)
+	return m, cmd
+}
+
+func (m Model) viewRecover() string {
+	var b strings.Builder
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render("🛟 Recover Vault")
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	if !m.Vault.HasRecoveryKey() {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", store.ErrNoRecoveryKey.Error())))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Esc back"))
+		content := boxStyle.Width(50).Render(b.String())
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+	}
+
+	// Input fields
+	labels := []string{"Recovery Key", "New Password", "Confirm New Password"}
+	for i, input := range m.recoveryInputs {
+		style := blurredStyle
+		if i == m.recoveryFocused {
+			style = focusedStyle
+		}
+		b.WriteString(style.Render(labels[i]))
+		b.WriteString("\n")
+		b.WriteString(input.View())
+		b.WriteString("\n\n")
+	}
+
+	// Error message
+	if m.err != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("✗ %s", m.err.Error())))
+		b.WriteString("\n")
+	}
+
+	// Help
+	b.WriteString(helpStyle.Render("Tab/↓ next • Shift+Tab/↑ prev • Enter reset • Esc back"))
+
+	// Center the content
+	content := boxStyle.Width(50).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
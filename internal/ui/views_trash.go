@@ -0,0 +1,165 @@
+package ui
+
+import (
+	"fmt"
+	"lockin/internal/store"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func (m Model) updateTrash(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		// Waiting for confirmation of a permanent delete
+		if m.purgeTarget != nil {
+			switch msg.String() {
+			case "y", "Y":
+				name := m.purgeTarget.Name
+				syncResult, err := m.Vault.Purge(m.purgeTarget.ID)
+				m.purgeTarget = nil
+				if err != nil {
+					return m, m.setToast("✗ Failed to delete permanently")
+				}
+				_ = m.refreshTrash()
+				return m, m.setToast(formatSyncToast("Permanently deleted", name, syncResult))
+
+			case "n", "N", "esc", "q":
+				m.purgeTarget = nil
+			}
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "esc", "q":
+			m.trash = nil
+			m.trashCursor = 0
+			m.view = ViewList
+			return m, nil
+
+		case "up", "k":
+			if m.trashCursor > 0 {
+				m.trashCursor--
+			}
+
+		case "down", "j":
+			if m.trashCursor < len(m.trash)-1 {
+				m.trashCursor++
+			}
+
+		case "r":
+			if len(m.trash) == 0 {
+				return m, nil
+			}
+			entry := m.trash[m.trashCursor]
+			syncResult, err := m.Vault.Restore(entry.ID)
+			if err == store.ErrDuplicateEntry {
+				return m, m.setToast(fmt.Sprintf("✗ An entry named '%s' already exists", entry.Name))
+			}
+			if err != nil {
+				return m, m.setToast("✗ Failed to restore entry")
+			}
+			_ = m.refreshTrash()
+			_ = m.refreshPasswords()
+			return m, m.setToast(formatSyncToast("Restored", entry.Name, syncResult))
+
+		case "d":
+			if len(m.trash) > 0 {
+				entry := m.trash[m.trashCursor]
+				m.purgeTarget = &entry
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// refreshTrash loads the trashed entries from the vault into the model
+func (m *Model) refreshTrash() error {
+	entries, err := m.Vault.Trash()
+	if err != nil {
+		return err
+	}
+
+	m.trash = make([]PasswordEntry, len(entries))
+	for i, e := range entries {
+		m.trash[i] = FromStoreEntry(e)
+	}
+	if m.trashCursor >= len(m.trash) {
+		m.trashCursor = max(0, len(m.trash)-1)
+	}
+	return nil
+}
+
+func (m Model) viewTrash() string {
+	var b strings.Builder
+
+	// Header
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(primaryColor).
+		MarginBottom(1).
+		Render("🗑 Trash")
+
+	b.WriteString(header)
+	b.WriteString("\n\n")
+
+	if len(m.trash) == 0 {
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render("The trash is empty.")
+		b.WriteString(emptyMsg)
+		b.WriteString("\n")
+	} else {
+		start, end := getVisibleWindow(m.trashCursor, len(m.trash), maxVisible)
+
+		// Show scroll indicator for items above
+		if start > 0 {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  ↑ %d more above\n", start)))
+		}
+
+		for i := start; i < end; i++ {
+			entry := m.trash[i]
+			cursor := "  "
+			style := normalItemStyle
+			if m.trashCursor == i {
+				cursor = "▸ "
+				style = selectedItemStyle
+			}
+
+			deleted := time.Unix(entry.DeletedAt, 0).Format("2006-01-02")
+			b.WriteString(style.Render(fmt.Sprintf("%s%s", cursor, entry.Name)))
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  deleted %s", deleted)))
+			b.WriteString("\n")
+		}
+
+		// Show scroll indicator for items below
+		if end < len(m.trash) {
+			b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("  ↓ %d more below\n", len(m.trash)-end)))
+		}
+	}
+
+	// Retention notice
+	if days := store.Config.TrashRetentionDays; days > 0 {
+		b.WriteString("\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render(fmt.Sprintf("Entries are deleted permanently after %d days in the trash.", days)))
+		b.WriteString("\n")
+	}
+
+	// Permanent delete confirmation
+	if m.purgeTarget != nil {
+		b.WriteString("\n")
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Permanently delete '%s'? This cannot be undone.", m.purgeTarget.Name)))
+		b.WriteString("\n")
+		b.WriteString(helpStyle.Render("Y confirm • N/Esc cancel"))
+	} else {
+		b.WriteString(helpStyle.Render("↑/↓ navigate • r restore • d delete permanently • Esc/q back"))
+	}
+
+	// Center the content
+	content := boxStyle.Width(60).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "net"
+
+// listenUnix creates a Unix socket. There is no umask on this platform, so
+// access is left to the permissions of the directory holding it.
+func listenUnix(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
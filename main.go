@@ -1,17 +1,78 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"lockin/internal/store"
 	"lockin/internal/ui"
 )
 
 func main() {
-	p := tea.NewProgram(ui.New(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running program: %v\n", err)
+	keyfile := flag.String("keyfile", "", "path to the keyfile required to unlock the vault")
+	flag.Usage = usage
+	flag.Parse()
+	store.SetKeyfile(*keyfile)
+
+	var err error
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+		err = runTUI()
+	case "recover":
+		err = runRecover()
+	case "keyfile":
+		err = runKeyfile(flag.Args()[1:])
+	case "generate":
+		err = runGenerate(flag.Args()[1:])
+	case "audit":
+		err = runAudit()
+	case "breach-check":
+		err = runBreachCheck(flag.Args()[1:])
+	case "ssh-agent":
+		err = runSSHAgent(flag.Args()[1:])
+	case "ssh-import":
+		err = runSSHImport(flag.Args()[1:])
+	default:
+		fmt.Printf("Unknown command: %s\n\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// usage prints the command line help
+func usage() {
+	fmt.Println("Usage: lockin [--keyfile path] [command]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  (none)       open the vault in the terminal UI")
+	fmt.Println("  recover      reset a forgotten master password with the recovery key")
+	fmt.Println("  keyfile      require a new keyfile to unlock [path], or none [--clear]")
+	fmt.Println("  audit        report breached, reused, weak and old passwords and risky URLs")
+	fmt.Println("  breach-check look up passwords in a local Pwned Passwords file [--file path]")
+	fmt.Println("  generate     print a random password or passphrase (generate -h for options)")
+	fmt.Println("  ssh-agent    serve the vault's SSH keys to ssh [--socket path] [--no-confirm]")
+	fmt.Println("  ssh-import   import SSH private keys [file ...] (default ~/.ssh/id_*)")
+	fmt.Println()
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+}
+
+// runTUI runs the interactive terminal UI
+func runTUI() error {
+	m, err := ui.New()
+	if err != nil {
+		return err
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("running program: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"lockin/internal/store"
+)
+
+// stdin is shared so buffered input is not lost between prompts
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine prompts for a line of visible input
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readSecret prompts for input without echoing it, falling back to a plain
+// line read when stdin is not a terminal
+func readSecret(prompt string) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return readLine(prompt)
+	}
+
+	fmt.Print(prompt)
+	secret, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// readNewPassword prompts for a new master password twice and checks they match
+func readNewPassword() (string, error) {
+	password, err := readSecret("New master password: ")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", fmt.Errorf("master password is required")
+	}
+
+	confirm, err := readSecret("Confirm master password: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != password {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return password, nil
+}
+
+// unlockVault prompts for the master password and unlocks the vault
+func unlockVault(vault *store.FileVault) error {
+	if !vault.Exists() {
+		return store.ErrVaultNotFound
+	}
+	if wait := vault.UnlockDelay(); wait > 0 {
+		return fmt.Errorf("too many failed attempts, try again in %s", wait.Round(time.Second))
+	}
+
+	password, err := readSecret("Master password: ")
+	if err != nil {
+		return err
+	}
+	return vault.Unlock(password)
+}
@@ -9,9 +9,11 @@ import (
 
 // Entry represents a credential to import
 type Entry struct {
+	Type     string // optional: login (default), note, card, api_key, identity or database
 	Name     string
 	Username string
 	Password string
+	OTP      string // optional, otpauth:// URI or base32 secret
 	URL      string // optional
 	Notes    string // optional
 }
@@ -42,8 +44,13 @@ func main() {
 	}
 	defer vault.Close()
 
-	// Unlock vault with master password
-	if err := vault.Unlock(masterPassword); err != nil {
+	// Create the vault on first use, otherwise unlock it with the master password
+	if !vault.Exists() {
+		if err := vault.Create(masterPassword); err != nil {
+			fmt.Printf("Error creating vault: %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := vault.Unlock(masterPassword); err != nil {
 		fmt.Printf("Error unlocking vault: %v\n", err)
 		os.Exit(1)
 	}
@@ -55,9 +62,11 @@ func main() {
 
 	for _, e := range entries {
 		storeEntry := store.Entry{
+			Type:     store.EntryType(e.Type),
 			Name:     e.Name,
 			Username: e.Username,
 			Password: e.Password,
+			OTP:      e.OTP,
 			URL:      e.URL,
 			Notes:    e.Notes,
 		}